@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hashicorp/raft"
+
+	"raft3d/httpapi"
+	"raft3d/store"
+	"raft3d/telemetry"
+)
+
+func main() {
+	// CLI flags
+	id := flag.String("id", "", "Node ID")
+	httpAddr := flag.String("http", ":8080", "HTTP server bind address")
+	raftBind := flag.String("raft", "127.0.0.1:9000", "Raft bind address")
+	joinAddr := flag.String("join", "", "Comma-separated HTTP addresses (host:port) of cluster members to join through")
+	nonvoter := flag.Bool("nonvoter", false, "Join as a non-voting replica that serves stale reads but doesn't count toward the quorum")
+	peers := flag.String("peers", "", "Initial cluster members as comma-separated id=raft-addr pairs, including this node; start every node with the same list")
+	httpAdvertise := flag.String("http-advertise", "", "HTTP address other nodes use to reach this node (defaults to the Raft host with the -http port)")
+	forwardMode := flag.String("forward", httpapi.ForwardProxy, "How followers handle writes: \"proxy\" to the leader or \"redirect\" with a 307")
+	readConsistency := flag.String("read-consistency", httpapi.ConsistencyLeader, "Default read consistency: \"linearizable\", \"leader\" or \"stale\"")
+	dataDir := flag.String("data-dir", "data", "Directory for the Raft log store and snapshots")
+	snapshotRetain := flag.Int("snapshot-retain", 2, "Number of snapshots to keep on disk")
+	snapshotThreshold := flag.Uint64("snapshot-threshold", raft.DefaultConfig().SnapshotThreshold, "Log entries applied since the last snapshot before taking another")
+	snapshotInterval := flag.Duration("snapshot-interval", raft.DefaultConfig().SnapshotInterval, "How often to check whether a snapshot is due")
+	strategyName := flag.String("schedule-strategy", "fewest-assigned", "How the scheduler picks printers for unassigned jobs: \"fewest-assigned\", \"most-filament\" or \"round-robin\"")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 30*time.Second, "How long to wait for in-flight requests and the leadership transfer on shutdown")
+	flag.Parse()
+
+	newStrategy, ok := store.Strategies[*strategyName]
+	if !ok {
+		log.Fatalf("Invalid -schedule-strategy %q", *strategyName)
+	}
+
+	if *httpAdvertise == "" {
+		*httpAdvertise = advertiseHTTPAddr(*httpAddr, *raftBind)
+	}
+
+	peerList, err := store.ParsePeers(*peers)
+	if err != nil {
+		log.Fatalf("Invalid -peers: %v", err)
+	}
+	seeds := splitAddrs(*joinAddr)
+	if *joinAddr != "" && len(seeds) == 0 {
+		log.Fatalf("Invalid -join: no addresses")
+	}
+	if len(peerList) > 0 && len(seeds) > 0 {
+		log.Fatalf("-peers and -join are mutually exclusive")
+	}
+
+	// Installed before Raft starts so no metrics are lost
+	sink, err := telemetry.Setup()
+	if err != nil {
+		log.Fatalf("Failed to set up metrics: %v", err)
+	}
+
+	st, err := store.Open(store.Config{
+		ID:                *id,
+		RaftBind:          *raftBind,
+		HTTPAddr:          *httpAdvertise,
+		DataDir:           *dataDir,
+		SnapshotRetain:    *snapshotRetain,
+		SnapshotThreshold: *snapshotThreshold,
+		SnapshotInterval:  *snapshotInterval,
+		Bootstrap:         len(seeds) == 0,
+		Peers:             peerList,
+		Strategy:          newStrategy(),
+	})
+	if err != nil {
+		log.Fatalf("Failed to start raft node: %v", err)
+	}
+
+	server, err := httpapi.New(st, httpapi.Config{
+		ForwardMode:     *forwardMode,
+		ReadConsistency: *readConsistency,
+		Metrics:         sink,
+	})
+	if err != nil {
+		log.Fatalf("Invalid flags: %v", err)
+	}
+
+	// Join an existing cluster
+	if len(seeds) > 0 {
+		if err := httpapi.Join(seeds, *id, *raftBind, *httpAdvertise, !*nonvoter); err != nil {
+			log.Fatalf("Failed to join cluster: %v", err)
+		}
+	}
+
+	httpServer := &http.Server{Addr: *httpAddr, Handler: server.Handler()}
+	httpServer.RegisterOnShutdown(server.CloseStreams)
+	go func() {
+		log.Printf("HTTP server listening on %s", *httpAddr)
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("HTTP server failed: %v", err)
+		}
+	}()
+
+	// Drain on SIGINT or SIGTERM: finish in-flight requests, then hand off
+	// leadership and stop Raft
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+
+	// Nodes started from -peers never sent a /join, so nothing has recorded
+	// their HTTP address yet
+	if len(peerList) > 0 {
+		go httpapi.RegisterWithLeader(ctx, st, *httpAdvertise)
+	}
+
+	<-ctx.Done()
+	stop()
+	log.Println("Shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("HTTP server shutdown: %v", err)
+	}
+	if err := st.Shutdown(shutdownCtx); err != nil {
+		log.Fatalf("Failed to shut down raft node: %v", err)
+	}
+	log.Println("Shutdown complete")
+}
+
+// splitAddrs splits a comma-separated address list, ignoring blank entries
+// and the spaces around each address
+func splitAddrs(list string) []string {
+	var addrs []string
+	for _, addr := range strings.Split(list, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// advertiseHTTPAddr derives a routable HTTP address from the bind address,
+// borrowing the host from the Raft address when the bind host is empty
+func advertiseHTTPAddr(httpBind, raftBind string) string {
+	host, port, err := net.SplitHostPort(httpBind)
+	if err != nil || host != "" {
+		return httpBind
+	}
+
+	raftHost, _, err := net.SplitHostPort(raftBind)
+	if err != nil {
+		return httpBind
+	}
+
+	return net.JoinHostPort(raftHost, port)
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitAddrs(t *testing.T) {
+	tests := []struct {
+		list string
+		want []string
+	}{
+		{list: ""},
+		{list: " , ,"},
+		{list: "127.0.0.1:8081", want: []string{"127.0.0.1:8081"}},
+		{list: "127.0.0.1:8081,127.0.0.1:8082", want: []string{"127.0.0.1:8081", "127.0.0.1:8082"}},
+		{list: " 127.0.0.1:8081 , ,127.0.0.1:8082,", want: []string{"127.0.0.1:8081", "127.0.0.1:8082"}},
+	}
+
+	for _, tt := range tests {
+		if got := splitAddrs(tt.list); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitAddrs(%q) = %q, want %q", tt.list, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package fsm
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-msgpack/v2/codec"
+	"github.com/hashicorp/raft"
+)
+
+func TestDecodeCommand(t *testing.T) {
+	weight := 40.0
+	current, err := EncodeCommand(Command{Type: CommandUpdateFilament, FilamentID: "filament-1", RemainingWeight: &weight})
+	if err != nil {
+		t.Fatalf("EncodeCommand: %v", err)
+	}
+
+	// EncodeCommand always stamps a version this node knows
+	newer := bytes.NewBuffer([]byte{commandFormatMsgpack})
+	if err := codec.NewEncoder(newer, msgpackHandle).Encode(&Command{Version: commandSchemaVersion + 1, Type: CommandCreatePrinter}); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		want    Command
+		wantErr string
+	}{
+		{
+			name: "msgpack",
+			data: string(current),
+			want: Command{Version: commandSchemaMsgpack, Type: CommandUpdateFilament, FilamentID: "filament-1", RemainingWeight: &weight},
+		},
+		{
+			name: "legacy submit_job",
+			data: `{"type":"submit_job","job":{"id":"job-1","status":"queued","printer_id":"p1","filament_weight":12.5}}`,
+			want: Command{Type: CommandSubmitJob, Job: &PrintJob{ID: "job-1", Status: "queued", PrinterID: "p1", FilamentWeight: 12.5}},
+		},
+		{
+			name: "legacy update_printer_status",
+			data: `{"type":"update_printer_status","printer_id":"p1","status":"printing","job_id":"job-1"}`,
+			want: Command{Type: CommandUpdatePrinterStatus, PrinterID: "p1", Status: "printing", JobID: "job-1"},
+		},
+		{name: "newer version", data: newer.String(), wantErr: "newer than supported"},
+		{name: "unknown legacy type", data: `{"type":"reticulate_splines"}`, wantErr: "unknown command type"},
+		{name: "unknown format", data: "\x02abc", wantErr: "unknown command format"},
+		{name: "empty", data: "", wantErr: "empty command"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, err := decodeCommand([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("decodeCommand error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeCommand: %v", err)
+			}
+			if !reflect.DeepEqual(cmd, tt.want) {
+				t.Errorf("decoded %+v, want %+v", cmd, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyStopsOnUndecodableEntry(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Apply skipped an entry it could not decode")
+		}
+	}()
+
+	New().Apply(&raft.Log{Index: 1, Data: []byte{0x02}})
+}
+
+func TestCommandSchemaVersion(t *testing.T) {
+	index := uint64(0)
+
+	tests := []struct {
+		name string
+		cmd  Command
+		want uint8
+	}{
+		{"plain", Command{Type: CommandCreatePrinter}, commandSchemaMsgpack},
+		{"idempotency key", Command{Type: CommandCreatePrinter, IdempotencyKey: "k"}, commandSchemaIdempotency},
+		{"condition on version 0", Command{Type: CommandUnloadFilament, CASIndex: &index}, commandSchemaCAS},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := EncodeCommand(tt.cmd)
+			if err != nil {
+				t.Fatalf("EncodeCommand: %v", err)
+			}
+			cmd, err := decodeCommand(data)
+			if err != nil {
+				t.Fatalf("decodeCommand: %v", err)
+			}
+			if cmd.Version != tt.want {
+				t.Errorf("version %d, want %d", cmd.Version, tt.want)
+			}
+		})
+	}
+}
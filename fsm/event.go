@@ -0,0 +1,77 @@
+package fsm
+
+import "slices"
+
+// Entity kinds named in events
+const (
+	KindJob      = "job"
+	KindPrinter  = "printer"
+	KindFilament = "filament"
+	KindNode     = "node"
+)
+
+// Event describes one applied command: the log index it was applied at and
+// the state of every entity it changed. A Reset event is sent instead when
+// the whole state is replaced from a snapshot; its Index is left for the
+// caller to fill in, since Restore isn't told the snapshot's index.
+type Event struct {
+	Index   uint64   `json:"index"`
+	Command string   `json:"command,omitempty"`
+	Changes []Change `json:"changes,omitempty"`
+	Reset   bool     `json:"reset,omitempty"`
+}
+
+// Change is the new state of one entity, or its removal
+type Change struct {
+	Kind    string      `json:"kind"`
+	ID      string      `json:"id"`
+	Deleted bool        `json:"deleted,omitempty"`
+	Entity  interface{} `json:"entity,omitempty"`
+}
+
+// The helpers below are the only writers of the entity maps, so every change
+// made while applying a command is recorded for its event. Jobs and printers
+// are stamped with the index of the entry being applied. The caller holds
+// f.mu.
+
+func (f *FSM) putJob(job *PrintJob) {
+	job.ModifyIndex = f.index
+	f.jobs[job.ID] = *job
+	f.record(Change{Kind: KindJob, ID: job.ID, Entity: *job})
+}
+
+func (f *FSM) putPrinter(printer *Printer) {
+	printer.ModifyIndex = f.index
+	f.printers[printer.ID] = *printer
+
+	recorded := *printer
+	recorded.Queue = slices.Clone(printer.Queue)
+	f.record(Change{Kind: KindPrinter, ID: printer.ID, Entity: recorded})
+}
+
+func (f *FSM) putFilament(filament Filament) {
+	f.filaments[filament.ID] = filament
+	f.record(Change{Kind: KindFilament, ID: filament.ID, Entity: filament})
+}
+
+func (f *FSM) deleteFilament(id string) {
+	delete(f.filaments, id)
+	f.record(Change{Kind: KindFilament, ID: id, Deleted: true})
+}
+
+func (f *FSM) putNode(node NodeMeta) {
+	f.nodes[node.ID] = node
+	f.record(Change{Kind: KindNode, ID: node.ID, Entity: node})
+}
+
+// record adds a change to the current command's event, keeping only the
+// latest state of an entity written more than once
+func (f *FSM) record(change Change) {
+	for i, recorded := range f.changes {
+		if recorded.Kind == change.Kind && recorded.ID == change.ID {
+			f.changes[i] = change
+			return
+		}
+	}
+	f.changes = append(f.changes, change)
+}
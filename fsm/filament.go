@@ -0,0 +1,11 @@
+package fsm
+
+// Filament represents a spool of filament that can be loaded into a printer
+type Filament struct {
+	ID              string  `json:"id"`
+	Type            string  `json:"type"` // "PLA", "PETG", "ABS", "TPU"
+	Color           string  `json:"color"`
+	TotalWeight     float64 `json:"total_weight"`
+	RemainingWeight float64 `json:"remaining_weight"`
+	PrinterID       string  `json:"printer_id,omitempty"`
+}
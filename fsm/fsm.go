@@ -0,0 +1,771 @@
+// Package fsm implements the replicated Raft state machine for printers,
+// filaments and print jobs. Every change is a Command applied through the Raft
+// log, so any service can embed the FSM and get the same state on every node.
+package fsm
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/raft"
+)
+
+// PrintJob represents a print job stored in Raft logs
+type PrintJob struct {
+	ID             string  `json:"id"`
+	Status         string  `json:"status"` // see job_status.go
+	PrinterID      string  `json:"printer_id"`
+	FilamentID     string  `json:"filament_id"`
+	FilamentWeight float64 `json:"filament_weight"`
+
+	// Constraints restrict which printers may run the job. Jobs submitted
+	// without a printer wait in the unassigned pool until the scheduler
+	// assigns them to a compatible printer.
+	Constraints *JobConstraints `json:"constraints,omitempty"`
+
+	// ModifyIndex is the index of the log entry that last changed the job
+	ModifyIndex uint64 `json:"modify_index"`
+}
+
+// JobConstraints describes the printer and filament a job needs
+type JobConstraints struct {
+	Material    string       `json:"material,omitempty"`     // filament type, e.g. "PLA"
+	Color       string       `json:"color,omitempty"`        // filament color
+	MinFilament float64      `json:"min_filament,omitempty"` // grams available on the spool
+	BuildVolume *BuildVolume `json:"build_volume,omitempty"` // smallest build volume that fits the part
+	Tags        []string     `json:"tags,omitempty"`         // printer must carry every tag
+}
+
+// NodeMeta is the replicated metadata for a cluster member, used to find the
+// leader's HTTP API from its Raft server ID
+type NodeMeta struct {
+	ID       string `json:"id"`
+	HTTPAddr string `json:"http_addr"`
+}
+
+// FSM implements the Raft state machine. mu guards every field below it:
+// Apply and Restore take it for writing. Handlers and the scheduler never
+// touch the maps: they go through accessor methods (reads.go), which take it
+// for reading and return copies.
+type FSM struct {
+	mu sync.RWMutex
+
+	jobs      map[string]PrintJob
+	printers  map[string]Printer
+	filaments map[string]Filament
+	nodes     map[string]NodeMeta
+
+	// unassigned holds the IDs of queued jobs waiting for the scheduler to
+	// pick a printer, oldest first
+	unassigned []string
+
+	// counters holds the last ID number allocated per entity kind
+	// ("printer", "job", "filament")
+	counters map[string]uint64
+
+	// idempotency maps idempotency keys to the result of the command that
+	// first used them; idempotencyOrder holds the same records oldest first,
+	// for expiry
+	idempotency      map[string]*idempotencyRecord
+	idempotencyOrder []*idempotencyRecord
+
+	// index is the log index of the entry being applied, and changes
+	// collects the entities it writes
+	index   uint64
+	changes []Change
+
+	// notify, if set, is called with the event of every successfully applied
+	// command, and with a Reset event after a restore. It must not block.
+	notify func(Event)
+}
+
+// New returns an FSM with empty state
+func New() *FSM {
+	return &FSM{
+		jobs:        make(map[string]PrintJob),
+		printers:    make(map[string]Printer),
+		filaments:   make(map[string]Filament),
+		nodes:       make(map[string]NodeMeta),
+		counters:    make(map[string]uint64),
+		idempotency: make(map[string]*idempotencyRecord),
+	}
+}
+
+// SetNotify sets a function called with the event of every successfully
+// applied command, and after the state is restored from a snapshot. It is
+// called in log order, outside the FSM's lock. It must not block, and must be
+// set before the FSM is handed to Raft.
+func (f *FSM) SetNotify(notify func(Event)) {
+	f.notify = notify
+}
+
+// Apply applies a Raft log entry, returning an ApplyResult. An entry that
+// can't be decoded, such as one written by a newer version, panics: every
+// replica that understands it applies it, so skipping it here would silently
+// fork this replica's state. The node has to be upgraded instead.
+func (f *FSM) Apply(logEntry *raft.Log) interface{} {
+	cmd, err := decodeCommand(logEntry.Data)
+	if err != nil {
+		log.Panicf("Cannot apply log entry %d, upgrade this node: %v", logEntry.Index, err)
+	}
+
+	start := time.Now()
+	f.mu.Lock()
+	f.index = logEntry.Index
+	f.changes = nil
+	result := f.applyIdempotent(cmd, logEntry.AppendedAt)
+	event := Event{Index: logEntry.Index, Command: cmd.Type.String(), Changes: f.changes}
+	f.changes = nil
+	f.mu.Unlock()
+	metrics.MeasureSinceWithLabels([]string{"fsm", "apply"}, start, []metrics.Label{{Name: "command", Value: cmd.Type.String()}})
+
+	// A command answered from an idempotency key changes nothing
+	if result.Err == nil && len(event.Changes) > 0 && f.notify != nil {
+		f.notify(event)
+	}
+
+	return result
+}
+
+// apply dispatches a decoded command; the caller holds f.mu
+func (f *FSM) apply(cmd Command) ApplyResult {
+	switch cmd.Type {
+	case CommandCreatePrinter:
+		return f.applyCreatePrinter(cmd)
+	case CommandSubmitJob:
+		return f.applySubmitJob(cmd)
+	case CommandUpdateJobStatus:
+		return f.applyUpdateJobStatus(cmd)
+	case CommandUpdatePrinterStatus:
+		return f.applyUpdatePrinterStatus(cmd)
+	case CommandCreateFilament:
+		return f.applyCreateFilament(cmd)
+	case CommandUpdateFilament:
+		return f.applyUpdateFilament(cmd)
+	case CommandDeleteFilament:
+		return f.applyDeleteFilament(cmd)
+	case CommandLoadFilament:
+		return f.applyLoadFilament(cmd)
+	case CommandUnloadFilament:
+		return f.applyUnloadFilament(cmd)
+	case CommandRegisterNode:
+		return f.applyRegisterNode(cmd)
+	case CommandAssignJob:
+		return f.applyAssignJob(cmd)
+	default:
+		return rejected(ErrInvalid, "Unknown command type %s", cmd.Type)
+	}
+}
+
+func (f *FSM) applyCreatePrinter(cmd Command) ApplyResult {
+	if cmd.Printer == nil {
+		return rejected(ErrInvalid, "Missing printer")
+	}
+	printer := *cmd.Printer
+
+	if err := checkPresetID(cmd, printer.ID); err != nil {
+		return ApplyResult{Err: err}
+	}
+	if printer.ID == "" {
+		printer.ID = f.allocateID("printer")
+	} else {
+		f.observeID("printer", printer.ID)
+	}
+
+	f.putPrinter(&printer)
+	return ApplyResult{Entity: printer}
+}
+
+func (f *FSM) applySubmitJob(cmd Command) ApplyResult {
+	if cmd.Job == nil {
+		return rejected(ErrInvalid, "Missing job")
+	}
+	job := *cmd.Job
+
+	if err := checkPresetID(cmd, job.ID); err != nil {
+		return ApplyResult{Err: err}
+	}
+
+	// Entries written before the FSM allocated IDs carry a job that was
+	// validated by the handler, with the printer updated by a separate entry
+	if job.ID != "" {
+		f.observeID("job", job.ID)
+		f.putJob(&job)
+		return ApplyResult{Entity: job}
+	}
+
+	if job.FilamentWeight <= 0 {
+		return rejected(ErrInvalid, "Filament weight must be positive")
+	}
+
+	// Without a printer the job goes to the pool for the scheduler
+	if job.PrinterID == "" {
+		job.ID = f.allocateID("job")
+		job.Status = JobQueued
+		f.putJob(&job)
+		f.unassigned = append(f.unassigned, job.ID)
+		return ApplyResult{Entity: job}
+	}
+
+	printer, exists := f.printers[job.PrinterID]
+	if !exists {
+		return rejected(ErrNotFound, "Printer %s not found", job.PrinterID)
+	}
+
+	if err := f.checkPrinterFor(job, printer); err != nil {
+		return ApplyResult{Err: err}
+	}
+
+	// Create the job and assign it to the printer in the same entry, so a
+	// queued job can never be left behind with an idle printer
+	job.ID = f.allocateID("job")
+	job.Status = JobQueued
+	f.assignJob(&job, &printer)
+
+	return ApplyResult{Entity: job}
+}
+
+func (f *FSM) applyAssignJob(cmd Command) ApplyResult {
+	jobID := cmd.JobID
+	if jobID == "" {
+		return rejected(ErrInvalid, "Missing job ID")
+	}
+
+	printerID := cmd.PrinterID
+	if printerID == "" {
+		return rejected(ErrInvalid, "Missing printer ID")
+	}
+
+	job, exists := f.jobs[jobID]
+	if !exists {
+		return rejected(ErrNotFound, "Job %s not found", jobID)
+	}
+
+	if job.PrinterID != "" || job.Status != JobQueued {
+		return rejected(ErrConflict, "Job %s is not waiting for a printer", jobID)
+	}
+
+	printer, exists := f.printers[printerID]
+	if !exists {
+		return rejected(ErrNotFound, "Printer %s not found", printerID)
+	}
+
+	// The scheduler only picks idle printers; if this one got busy since,
+	// the job stays in the pool for the next pass
+	if printer.Status != "idle" {
+		return rejected(ErrConflict, "Printer %s is busy", printerID)
+	}
+
+	if err := f.checkPrinterFor(job, printer); err != nil {
+		return ApplyResult{Err: err}
+	}
+
+	f.unassigned = removeJobID(f.unassigned, jobID)
+	f.assignJob(&job, &printer)
+
+	return ApplyResult{Entity: job}
+}
+
+// checkPrinterFor reports why printer can't take job, if it can't: it needs
+// a matching spool with enough filament left after the printer's current and
+// queued jobs, and must satisfy the job's constraints. The caller holds f.mu.
+func (f *FSM) checkPrinterFor(job PrintJob, printer Printer) error {
+	// Jobs print from whatever spool is loaded; an explicit filament must match it
+	if printer.FilamentID == "" {
+		return fsmError(ErrConflict, "No filament loaded in printer %s", printer.ID)
+	}
+	if job.FilamentID != "" && job.FilamentID != printer.FilamentID {
+		return fsmError(ErrConflict, "Filament %s is not loaded in printer %s", job.FilamentID, printer.ID)
+	}
+
+	// Jobs already assigned to the printer have first claim on the spool
+	filament := f.filaments[printer.FilamentID]
+	available := filament.RemainingWeight - f.reservedFilament(printer)
+	if available < job.FilamentWeight {
+		return fsmError(ErrConflict, "Not enough filament on %s: %.1fg available, %.1fg needed",
+			filament.ID, available, job.FilamentWeight)
+	}
+
+	c := job.Constraints
+	if c == nil {
+		return nil
+	}
+
+	if c.Material != "" && !strings.EqualFold(c.Material, filament.Type) {
+		return fsmError(ErrConflict, "Printer %s has %s loaded, job needs %s", printer.ID, filament.Type, c.Material)
+	}
+	if c.Color != "" && !strings.EqualFold(c.Color, filament.Color) {
+		return fsmError(ErrConflict, "Printer %s has %s filament loaded, job needs %s", printer.ID, filament.Color, c.Color)
+	}
+	if available < c.MinFilament {
+		return fsmError(ErrConflict, "Printer %s has %.1fg of filament available, job needs %.1fg", printer.ID, available, c.MinFilament)
+	}
+	if c.BuildVolume != nil && !printer.BuildVolume.Fits(*c.BuildVolume) {
+		return fsmError(ErrConflict, "Job does not fit the build volume of printer %s", printer.ID)
+	}
+	for _, tag := range c.Tags {
+		if !printer.HasTag(tag) {
+			return fsmError(ErrConflict, "Printer %s is missing tag %q", printer.ID, tag)
+		}
+	}
+
+	return nil
+}
+
+// assignJob gives a queued job to a printer that passed checkPrinterFor,
+// starting it if the printer is idle and queueing it otherwise, just as
+// advanceQueue starts the next queued job
+func (f *FSM) assignJob(job *PrintJob, printer *Printer) {
+	job.PrinterID = printer.ID
+	job.FilamentID = printer.FilamentID
+
+	if printer.Status == "idle" {
+		job.Status = JobPrinting
+		printer.Status = "printing"
+		printer.CurrentJobID = job.ID
+	} else {
+		printer.Queue = append(printer.Queue, job.ID)
+	}
+	f.putJob(job)
+
+	printer.JobsAssigned++
+	f.putPrinter(printer)
+}
+
+func (f *FSM) applyUpdateJobStatus(cmd Command) ApplyResult {
+	jobID := cmd.JobID
+	if jobID == "" {
+		return rejected(ErrInvalid, "Missing job ID")
+	}
+
+	status := cmd.Status
+	if status == "" {
+		return rejected(ErrInvalid, "Missing status")
+	}
+
+	job, exists := f.jobs[jobID]
+	if !exists {
+		return rejected(ErrNotFound, "Job %s not found", jobID)
+	}
+
+	if cmd.Version == 0 {
+		return f.applyLegacyJobStatus(job, status)
+	}
+
+	if !validJobStatus(status) {
+		return rejected(ErrInvalid, "Unknown job status %q", status)
+	}
+
+	if err := checkModifyIndex(cmd, "Job", jobID, job.ModifyIndex); err != nil {
+		return ApplyResult{Err: err}
+	}
+
+	if !canTransitionJob(job.Status, status) {
+		return rejected(ErrConflict, "Job %s cannot move from %s to %s", jobID, job.Status, status)
+	}
+
+	printer, hasPrinter := f.printers[job.PrinterID]
+
+	// A job waiting in the pool or a printer's queue starts only when it is
+	// assigned or promoted
+	if status == JobPrinting && job.PrinterID == "" {
+		return rejected(ErrConflict, "Job %s is not assigned to a printer", jobID)
+	}
+	if status == JobPrinting && hasPrinter && printer.CurrentJobID != jobID {
+		return rejected(ErrConflict, "Job %s is waiting in the queue of printer %s", jobID, printer.ID)
+	}
+
+	job.Status = status
+	f.putJob(&job)
+
+	// Only a completed print consumes filament from the job's spool
+	if status == JobCompleted {
+		if filament, exists := f.filaments[job.FilamentID]; exists {
+			filament.RemainingWeight -= job.FilamentWeight
+			f.putFilament(filament)
+		}
+	}
+
+	// A finished job hands its printer to the next queued job, or leaves the
+	// queue or pool if it never started
+	if jobFinished(status) && job.PrinterID == "" {
+		f.unassigned = removeJobID(f.unassigned, jobID)
+	}
+	if jobFinished(status) && hasPrinter {
+		if printer.CurrentJobID == jobID {
+			f.advanceQueue(&printer)
+		} else {
+			printer.Queue = removeJobID(printer.Queue, jobID)
+		}
+		f.putPrinter(&printer)
+	}
+
+	if jobFinished(status) {
+		metrics.IncrCounterWithLabels([]string{"jobs", "finished"}, 1, []metrics.Label{{Name: "status", Value: status}})
+	}
+
+	return ApplyResult{Entity: job}
+}
+
+// applyLegacyJobStatus replays an update_job_status entry from the legacy JSON
+// log the way the nodes that wrote it applied it: any status is accepted, and
+// only completion deducts the job's filament and frees its printer. Those
+// nodes had no transition table or queues, and their logs must replay to the
+// same state.
+func (f *FSM) applyLegacyJobStatus(job PrintJob, status string) ApplyResult {
+	job.Status = status
+	f.putJob(&job)
+
+	if status != JobCompleted {
+		return ApplyResult{Entity: job}
+	}
+
+	if filament, exists := f.filaments[job.FilamentID]; exists {
+		filament.RemainingWeight -= job.FilamentWeight
+		f.putFilament(filament)
+	}
+	if printer, exists := f.printers[job.PrinterID]; exists {
+		printer.Status = "idle"
+		printer.CurrentJobID = ""
+		f.putPrinter(&printer)
+	}
+
+	return ApplyResult{Entity: job}
+}
+
+// advanceQueue promotes the printer's next queued job to printing, or marks
+// the printer idle when its queue is empty
+func (f *FSM) advanceQueue(printer *Printer) {
+	if len(printer.Queue) == 0 {
+		printer.Status = "idle"
+		printer.CurrentJobID = ""
+		return
+	}
+
+	nextID := printer.Queue[0]
+	printer.Queue = append([]string(nil), printer.Queue[1:]...)
+	printer.Status = "printing"
+	printer.CurrentJobID = nextID
+
+	next := f.jobs[nextID]
+	next.Status = JobPrinting
+	f.putJob(&next)
+}
+
+// reservedFilament is the filament promised to a printer's current and queued
+// jobs; the caller holds f.mu
+func (f *FSM) reservedFilament(printer Printer) float64 {
+	var reserved float64
+	if current, exists := f.jobs[printer.CurrentJobID]; exists && !jobFinished(current.Status) {
+		reserved += current.FilamentWeight
+	}
+	for _, jobID := range printer.Queue {
+		reserved += f.jobs[jobID].FilamentWeight
+	}
+	return reserved
+}
+
+func removeJobID(queue []string, jobID string) []string {
+	remaining := make([]string, 0, len(queue))
+	for _, id := range queue {
+		if id != jobID {
+			remaining = append(remaining, id)
+		}
+	}
+	return remaining
+}
+
+func (f *FSM) applyUpdatePrinterStatus(cmd Command) ApplyResult {
+	printerID := cmd.PrinterID
+	if printerID == "" {
+		return rejected(ErrInvalid, "Missing printer ID")
+	}
+
+	status := cmd.Status
+	if status == "" {
+		return rejected(ErrInvalid, "Missing status")
+	}
+
+	printer, exists := f.printers[printerID]
+	if !exists {
+		return rejected(ErrNotFound, "Printer %s not found", printerID)
+	}
+
+	printer.Status = status
+
+	// If a job ID is provided, update it
+	if cmd.JobID != "" {
+		printer.CurrentJobID = cmd.JobID
+	}
+
+	f.putPrinter(&printer)
+	return ApplyResult{Entity: printer}
+}
+
+func (f *FSM) applyCreateFilament(cmd Command) ApplyResult {
+	if cmd.Filament == nil {
+		return rejected(ErrInvalid, "Missing filament")
+	}
+	filament := *cmd.Filament
+
+	if err := checkPresetID(cmd, filament.ID); err != nil {
+		return ApplyResult{Err: err}
+	}
+	if filament.ID == "" {
+		filament.ID = f.allocateID("filament")
+	} else {
+		f.observeID("filament", filament.ID)
+	}
+
+	f.putFilament(filament)
+	return ApplyResult{Entity: filament}
+}
+
+func (f *FSM) applyUpdateFilament(cmd Command) ApplyResult {
+	filamentID := cmd.FilamentID
+	if filamentID == "" {
+		return rejected(ErrInvalid, "Missing filament ID")
+	}
+
+	filament, exists := f.filaments[filamentID]
+	if !exists {
+		return rejected(ErrNotFound, "Filament %s not found", filamentID)
+	}
+
+	if cmd.FilamentType != nil {
+		filament.Type = *cmd.FilamentType
+	}
+	if cmd.Color != nil {
+		filament.Color = *cmd.Color
+	}
+	if cmd.RemainingWeight != nil {
+		filament.RemainingWeight = *cmd.RemainingWeight
+	}
+
+	if filament.Type == "" {
+		return rejected(ErrInvalid, "Filament type must not be empty")
+	}
+	if filament.RemainingWeight < 0 || filament.RemainingWeight > filament.TotalWeight {
+		return rejected(ErrInvalid, "Remaining weight must be between 0 and the total weight of %.1fg", filament.TotalWeight)
+	}
+
+	// The loaded printer's current and queued jobs were accepted against
+	// what the spool had left, so it can't drop below what they need
+	if printer, exists := f.printers[filament.PrinterID]; exists {
+		if reserved := f.reservedFilament(printer); filament.RemainingWeight < reserved {
+			return rejected(ErrConflict, "Printer %s has %.1fg of filament %s reserved for its jobs",
+				printer.ID, reserved, filamentID)
+		}
+	}
+
+	f.putFilament(filament)
+	return ApplyResult{Entity: filament}
+}
+
+func (f *FSM) applyDeleteFilament(cmd Command) ApplyResult {
+	filamentID := cmd.FilamentID
+	if filamentID == "" {
+		return rejected(ErrInvalid, "Missing filament ID")
+	}
+
+	filament, exists := f.filaments[filamentID]
+	if !exists {
+		return rejected(ErrNotFound, "Filament %s not found", filamentID)
+	}
+
+	if filament.PrinterID != "" {
+		return rejected(ErrConflict, "Filament %s is loaded in printer %s", filamentID, filament.PrinterID)
+	}
+
+	f.deleteFilament(filamentID)
+	return ApplyResult{Entity: filament}
+}
+
+func (f *FSM) applyLoadFilament(cmd Command) ApplyResult {
+	printerID := cmd.PrinterID
+	if printerID == "" {
+		return rejected(ErrInvalid, "Missing printer ID")
+	}
+
+	filamentID := cmd.FilamentID
+	if filamentID == "" {
+		return rejected(ErrInvalid, "Missing filament ID")
+	}
+
+	printer, exists := f.printers[printerID]
+	if !exists {
+		return rejected(ErrNotFound, "Printer %s not found", printerID)
+	}
+
+	filament, exists := f.filaments[filamentID]
+	if !exists {
+		return rejected(ErrNotFound, "Filament %s not found", filamentID)
+	}
+
+	if err := checkModifyIndex(cmd, "Printer", printerID, printer.ModifyIndex); err != nil {
+		return ApplyResult{Err: err}
+	}
+
+	if filament.PrinterID != "" && filament.PrinterID != printerID {
+		return rejected(ErrConflict, "Filament %s is already loaded in printer %s", filamentID, filament.PrinterID)
+	}
+
+	// Swapping spools mid-print would make the job deduct from the wrong one
+	if printer.Status == "printing" {
+		return rejected(ErrConflict, "Printer %s is busy", printerID)
+	}
+
+	// Swap out whatever spool the printer was holding
+	if printer.FilamentID != "" && printer.FilamentID != filamentID {
+		if previous, exists := f.filaments[printer.FilamentID]; exists {
+			previous.PrinterID = ""
+			f.putFilament(previous)
+		}
+	}
+
+	printer.FilamentID = filamentID
+	filament.PrinterID = printerID
+	f.putPrinter(&printer)
+	f.putFilament(filament)
+	return ApplyResult{Entity: printer}
+}
+
+func (f *FSM) applyUnloadFilament(cmd Command) ApplyResult {
+	printerID := cmd.PrinterID
+	if printerID == "" {
+		return rejected(ErrInvalid, "Missing printer ID")
+	}
+
+	printer, exists := f.printers[printerID]
+	if !exists {
+		return rejected(ErrNotFound, "Printer %s not found", printerID)
+	}
+
+	if err := checkModifyIndex(cmd, "Printer", printerID, printer.ModifyIndex); err != nil {
+		return ApplyResult{Err: err}
+	}
+
+	if printer.Status == "printing" {
+		return rejected(ErrConflict, "Printer %s is busy", printerID)
+	}
+
+	if filament, exists := f.filaments[printer.FilamentID]; exists {
+		filament.PrinterID = ""
+		f.putFilament(filament)
+	}
+
+	printer.FilamentID = ""
+	f.putPrinter(&printer)
+	return ApplyResult{Entity: printer}
+}
+
+func (f *FSM) applyRegisterNode(cmd Command) ApplyResult {
+	if cmd.Node == nil {
+		return rejected(ErrInvalid, "Missing node")
+	}
+	node := *cmd.Node
+
+	f.putNode(node)
+	return ApplyResult{Entity: node}
+}
+
+// checkPresetID rejects an ID chosen outside the FSM, which only legacy JSON
+// entries may carry: accepting one from a current command would let it
+// overwrite an existing entity without any of the create checks
+func checkPresetID(cmd Command, id string) error {
+	if id != "" && cmd.Version != 0 {
+		return fsmError(ErrInvalid, "IDs are assigned by the cluster, %q can't be chosen", id)
+	}
+	return nil
+}
+
+// allocateID returns the next sequential ID for an entity kind. IDs are only
+// allocated here, while applying the log, so every replica picks the same one.
+func (f *FSM) allocateID(kind string) string {
+	f.counters[kind]++
+	return fmt.Sprintf("%s-%d", kind, f.counters[kind])
+}
+
+// observeID advances a kind's counter past an ID that was chosen outside the
+// FSM, as in log entries written before IDs were allocated here
+func (f *FSM) observeID(kind, id string) {
+	n, err := strconv.ParseUint(strings.TrimPrefix(id, kind+"-"), 10, 64)
+	if err == nil && n > f.counters[kind] {
+		f.counters[kind] = n
+	}
+}
+
+// Snapshot returns a point-in-time copy of the current state. Raft calls it
+// on the FSM goroutine, so no Apply runs while the maps are copied; Persist
+// then encodes the copy on another goroutine while Apply keeps mutating the
+// live maps. Only the maps are copied, not the serialized form, so this is
+// cheap next to Persist.
+func (f *FSM) Snapshot() (raft.FSMSnapshot, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return &Snapshot{
+		jobs:       maps.Clone(f.jobs),
+		printers:   f.clonePrinters(),
+		filaments:  maps.Clone(f.filaments),
+		nodes:      maps.Clone(f.nodes),
+		unassigned: slices.Clone(f.unassigned),
+		counters:   maps.Clone(f.counters),
+		// Records are never modified, only replaced
+		idempotency: slices.Clone(f.idempotencyOrder),
+	}, nil
+}
+
+// Restore restores the state from a snapshot. The current state is only
+// replaced once the whole snapshot has been read and verified.
+func (f *FSM) Restore(reader io.ReadCloser) error {
+	defer reader.Close()
+
+	s, err := readSnapshot(reader)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	f.jobs = s.jobs
+	f.printers = s.printers
+	f.filaments = s.filaments
+	f.nodes = s.nodes
+	f.unassigned = s.unassigned
+
+	f.idempotency = make(map[string]*idempotencyRecord, len(s.idempotency))
+	f.idempotencyOrder = s.idempotency
+	for _, record := range s.idempotency {
+		f.idempotency[record.Key] = record
+	}
+
+	// Rebuild ID counters from existing IDs for snapshots taken before they existed
+	f.counters = s.counters
+	if f.counters == nil {
+		f.counters = make(map[string]uint64)
+		for id := range f.printers {
+			f.observeID("printer", id)
+		}
+		for id := range f.jobs {
+			f.observeID("job", id)
+		}
+		for id := range f.filaments {
+			f.observeID("filament", id)
+		}
+	}
+	f.mu.Unlock()
+
+	if f.notify != nil {
+		f.notify(Event{Reset: true})
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package fsm
+
+import (
+	"errors"
+	"maps"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+// applyAt encodes cmd, applies it at the next log index with the given
+// AppendedAt and returns the result
+func applyAt(t *testing.T, f *FSM, cmd Command, at time.Time) ApplyResult {
+	t.Helper()
+
+	data, err := EncodeCommand(cmd)
+	if err != nil {
+		t.Fatalf("EncodeCommand: %v", err)
+	}
+	return applyData(t, f, data, at)
+}
+
+func applyData(t *testing.T, f *FSM, data []byte, at time.Time) ApplyResult {
+	t.Helper()
+
+	f.mu.RLock()
+	index := f.index + 1
+	f.mu.RUnlock()
+
+	result, ok := f.Apply(&raft.Log{Index: index, Data: data, AppendedAt: at}).(ApplyResult)
+	if !ok {
+		t.Fatalf("Apply did not return an ApplyResult")
+	}
+	return result
+}
+
+func mustApply(t *testing.T, f *FSM, cmd Command) interface{} {
+	t.Helper()
+
+	result := applyAt(t, f, cmd, time.Time{})
+	if result.Err != nil {
+		t.Fatalf("%s: %v", cmd.Type, result.Err)
+	}
+	return result.Entity
+}
+
+// newLoadedFSM returns an FSM with printer-1 holding a 100g spool
+func newLoadedFSM(t *testing.T) *FSM {
+	t.Helper()
+
+	f := New()
+	mustApply(t, f, Command{Type: CommandCreatePrinter, Printer: &Printer{Name: "A", Status: "idle"}})
+	mustApply(t, f, Command{Type: CommandCreateFilament, Filament: &Filament{Type: "PLA", Color: "red", TotalWeight: 100, RemainingWeight: 100}})
+	mustApply(t, f, Command{Type: CommandLoadFilament, PrinterID: "printer-1", FilamentID: "filament-1"})
+	return f
+}
+
+func TestPresetIDs(t *testing.T) {
+	tests := []struct {
+		name    string
+		legacy  string // a JSON entry, applied instead of cmd when set
+		cmd     Command
+		wantErr error
+		wantID  string
+	}{
+		{name: "legacy printer", legacy: `{"type":"create_printer","printer":{"id":"printer-7","name":"Old"}}`, wantID: "printer-7"},
+		{name: "legacy job", legacy: `{"type":"submit_job","job":{"id":"job-7","status":"queued","filament_weight":5}}`, wantID: "job-7"},
+		{name: "legacy filament", legacy: `{"type":"create_filament","filament":{"id":"filament-7","type":"PLA"}}`, wantID: "filament-7"},
+		{name: "printer", cmd: Command{Type: CommandCreatePrinter, Printer: &Printer{ID: "printer-1", Name: "Evil"}}, wantErr: ErrInvalid},
+		{name: "job", cmd: Command{Type: CommandSubmitJob, Job: &PrintJob{ID: "job-1", Status: JobCompleted, FilamentWeight: 5}}, wantErr: ErrInvalid},
+		{name: "filament", cmd: Command{Type: CommandCreateFilament, Filament: &Filament{ID: "filament-1", Type: "PLA"}}, wantErr: ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newLoadedFSM(t)
+			mustApply(t, f, Command{Type: CommandSubmitJob, Job: &PrintJob{PrinterID: "printer-1", FilamentWeight: 10}})
+			jobs, printers, filaments := maps.Clone(f.jobs), maps.Clone(f.printers), maps.Clone(f.filaments)
+
+			var result ApplyResult
+			if tt.legacy != "" {
+				result = applyData(t, f, []byte(tt.legacy), time.Time{})
+			} else {
+				result = applyAt(t, f, tt.cmd, time.Time{})
+			}
+
+			if tt.wantErr != nil {
+				if !errors.Is(result.Err, tt.wantErr) {
+					t.Fatalf("got %v, want %v", result.Err, tt.wantErr)
+				}
+				if !reflect.DeepEqual(f.jobs, jobs) || !reflect.DeepEqual(f.printers, printers) || !reflect.DeepEqual(f.filaments, filaments) {
+					t.Errorf("rejected command changed the state")
+				}
+				return
+			}
+			if result.Err != nil {
+				t.Fatalf("Apply: %v", result.Err)
+			}
+
+			// Later IDs are allocated past the legacy one
+			kind, _, _ := strings.Cut(tt.wantID, "-")
+			if got := f.counters[kind]; got != 7 {
+				t.Errorf("%s counter %d, want 7", kind, got)
+			}
+		})
+	}
+}
+
+func TestQueuePromotion(t *testing.T) {
+	f := newLoadedFSM(t)
+	for _, weight := range []float64{10, 20, 30} {
+		mustApply(t, f, Command{Type: CommandSubmitJob, Job: &PrintJob{PrinterID: "printer-1", FilamentWeight: weight}})
+	}
+
+	// The spool has 100g and 60g is reserved
+	over := applyAt(t, f, Command{Type: CommandSubmitJob, Job: &PrintJob{PrinterID: "printer-1", FilamentWeight: 41}}, time.Time{})
+	if !errors.Is(over.Err, ErrConflict) {
+		t.Fatalf("job over the reserved filament: got %v, want a conflict", over.Err)
+	}
+
+	steps := []struct {
+		job       string
+		status    string
+		current   string
+		queue     []string
+		statuses  map[string]string
+		remaining float64
+	}{
+		{"", "", "job-1", []string{"job-2", "job-3"}, map[string]string{"job-1": JobPrinting, "job-2": JobQueued, "job-3": JobQueued}, 100},
+		{"job-2", JobCancelled, "job-1", []string{"job-3"}, map[string]string{"job-2": JobCancelled}, 100},
+		{"job-1", JobCompleted, "job-3", nil, map[string]string{"job-1": JobCompleted, "job-3": JobPrinting}, 90},
+		{"job-3", JobFailed, "", nil, map[string]string{"job-3": JobFailed}, 90},
+	}
+
+	for _, step := range steps {
+		if step.job != "" {
+			mustApply(t, f, Command{Type: CommandUpdateJobStatus, JobID: step.job, Status: step.status})
+		}
+
+		printer, _ := f.Printer("printer-1")
+		if printer.CurrentJobID != step.current || len(printer.Queue) != len(step.queue) ||
+			(len(step.queue) > 0 && !reflect.DeepEqual(printer.Queue, step.queue)) {
+			t.Fatalf("after %s %s: printer runs %q with queue %v, want %q with %v",
+				step.job, step.status, printer.CurrentJobID, printer.Queue, step.current, step.queue)
+		}
+		wantStatus := "idle"
+		if step.current != "" {
+			wantStatus = "printing"
+		}
+		if printer.Status != wantStatus {
+			t.Errorf("after %s %s: printer %s, want %s", step.job, step.status, printer.Status, wantStatus)
+		}
+		for jobID, want := range step.statuses {
+			if job, _ := f.Job(jobID); job.Status != want {
+				t.Errorf("after %s %s: %s is %s, want %s", step.job, step.status, jobID, job.Status, want)
+			}
+		}
+		if filament, _ := f.Filament("filament-1"); filament.RemainingWeight != step.remaining {
+			t.Errorf("after %s %s: %.1fg left, want %.1fg", step.job, step.status, filament.RemainingWeight, step.remaining)
+		}
+	}
+}
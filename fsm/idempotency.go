@@ -0,0 +1,176 @@
+package fsm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// IdempotencyTTL is how long the result of a command carrying an idempotency
+// key is remembered. Time is measured by the AppendedAt stamps the leader puts
+// on log entries, not by local clocks, so every replica forgets a key at the
+// same entry.
+const IdempotencyTTL = 24 * time.Hour
+
+// idempotencyRecord is the result of the first command that used a key
+type idempotencyRecord struct {
+	Key     string    `json:"key"`
+	Digest  string    `json:"digest"` // of the command, without its key
+	Expires time.Time `json:"expires"`
+
+	// Kind is the entity's kind (KindJob, ...), missing from records made
+	// before it was stored
+	Kind   string          `json:"kind,omitempty"`
+	Entity json.RawMessage `json:"entity,omitempty"`
+	Error  *recordedError  `json:"error,omitempty"`
+}
+
+// recordedError is a rejection in a form that survives snapshots
+type recordedError struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// errorKinds names the rejection kinds for recordedError
+var errorKinds = map[string]error{
+	"not_found":           ErrNotFound,
+	"conflict":            ErrConflict,
+	"invalid":             ErrInvalid,
+	"precondition_failed": ErrPreconditionFailed,
+}
+
+// applyIdempotent applies cmd unless its idempotency key was seen before, in
+// which case the first result is returned again. A key reused for a different
+// command is rejected. now is the entry's AppendedAt; the caller holds f.mu.
+func (f *FSM) applyIdempotent(cmd Command, now time.Time) ApplyResult {
+	f.expireIdempotencyKeys(now)
+
+	key := cmd.IdempotencyKey
+	if key == "" {
+		return f.apply(cmd)
+	}
+
+	digest, err := commandDigest(cmd)
+	if err != nil {
+		return ApplyResult{Err: err}
+	}
+
+	if record, exists := f.idempotency[key]; exists {
+		if record.Digest != digest {
+			return rejected(ErrInvalid, "Idempotency key %q was already used for a different request", key)
+		}
+		return record.result()
+	}
+
+	result := f.apply(cmd)
+
+	record := &idempotencyRecord{Key: key, Digest: digest, Expires: now.Add(IdempotencyTTL)}
+	if result.Err != nil {
+		record.Error = recordError(result.Err)
+	} else {
+		if record.Entity, err = json.Marshal(result.Entity); err != nil {
+			return ApplyResult{Err: err}
+		}
+		record.Kind = entityKind(result.Entity)
+	}
+	f.idempotency[key] = record
+	f.idempotencyOrder = append(f.idempotencyOrder, record)
+
+	return result
+}
+
+// expireIdempotencyKeys forgets the keys that expired by now, oldest first.
+// Entries without a timestamp don't advance time.
+func (f *FSM) expireIdempotencyKeys(now time.Time) {
+	if now.IsZero() {
+		return
+	}
+
+	expired := 0
+	for _, record := range f.idempotencyOrder {
+		if now.Before(record.Expires) {
+			break
+		}
+		delete(f.idempotency, record.Key)
+		expired++
+	}
+	if expired > 0 {
+		f.idempotencyOrder = append([]*idempotencyRecord(nil), f.idempotencyOrder[expired:]...)
+	}
+}
+
+// result rebuilds the recorded result. The entity is decoded back into its
+// type, so callers see the same result as for the first command; records
+// without a kind return its JSON encoding, which handlers send as is.
+func (r *idempotencyRecord) result() ApplyResult {
+	if r.Error != nil {
+		kind := errorKinds[r.Error.Kind]
+		if kind == nil {
+			kind = ErrInvalid
+		}
+		return ApplyResult{Err: &FSMError{Kind: kind, Message: r.Error.Message}}
+	}
+
+	switch r.Kind {
+	case KindJob:
+		return ApplyResult{Entity: decodeEntity[PrintJob](r.Entity)}
+	case KindPrinter:
+		return ApplyResult{Entity: decodeEntity[Printer](r.Entity)}
+	case KindFilament:
+		return ApplyResult{Entity: decodeEntity[Filament](r.Entity)}
+	case KindNode:
+		return ApplyResult{Entity: decodeEntity[NodeMeta](r.Entity)}
+	default:
+		return ApplyResult{Entity: r.Entity}
+	}
+}
+
+// decodeEntity decodes a recorded entity, falling back to its JSON encoding
+func decodeEntity[T any](raw json.RawMessage) interface{} {
+	var entity T
+	if err := json.Unmarshal(raw, &entity); err != nil {
+		return raw
+	}
+	return entity
+}
+
+// entityKind names the kind of an entity returned by a command
+func entityKind(entity interface{}) string {
+	switch entity.(type) {
+	case PrintJob:
+		return KindJob
+	case Printer:
+		return KindPrinter
+	case Filament:
+		return KindFilament
+	case NodeMeta:
+		return KindNode
+	default:
+		return ""
+	}
+}
+
+func recordError(err error) *recordedError {
+	for name, kind := range errorKinds {
+		if errors.Is(err, kind) {
+			return &recordedError{Kind: name, Message: err.Error()}
+		}
+	}
+	return &recordedError{Kind: "invalid", Message: err.Error()}
+}
+
+// commandDigest identifies what a command asks for, so a retry can be told
+// apart from another request reusing its key
+func commandDigest(cmd Command) (string, error) {
+	cmd.IdempotencyKey = ""
+
+	encoded, err := EncodeCommand(cmd)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:]), nil
+}
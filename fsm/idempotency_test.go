@@ -0,0 +1,56 @@
+package fsm
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyKeyExpiry(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	create := Command{Type: CommandCreatePrinter, Printer: &Printer{Name: "A"}, IdempotencyKey: "k1"}
+
+	tests := []struct {
+		name    string
+		cmd     Command
+		at      time.Time
+		wantID  string
+		wantErr error
+	}{
+		{"first request", create, start, "printer-1", nil},
+		{"retry", create, start.Add(time.Hour), "printer-1", nil},
+		{"retry without a timestamp", create, time.Time{}, "printer-1", nil},
+		{"key reused for another request", Command{Type: CommandCreatePrinter, Printer: &Printer{Name: "B"}, IdempotencyKey: "k1"}, start.Add(2 * time.Hour), "", ErrInvalid},
+		{"retry just before expiry", create, start.Add(IdempotencyTTL - time.Second), "printer-1", nil},
+		{"retry after expiry", create, start.Add(IdempotencyTTL), "printer-2", nil},
+		{"retry of the new request", create, start.Add(IdempotencyTTL + time.Hour), "printer-2", nil},
+	}
+
+	// Steps run in order against one FSM, since expiry follows log time
+	f := New()
+	for _, tt := range tests {
+		result := applyAt(t, f, tt.cmd, tt.at)
+		if tt.wantErr != nil {
+			if !errors.Is(result.Err, tt.wantErr) {
+				t.Errorf("%s: got %v, want %v", tt.name, result.Err, tt.wantErr)
+			}
+			continue
+		}
+		if result.Err != nil {
+			t.Fatalf("%s: %v", tt.name, result.Err)
+		}
+
+		// A replayed result has the type of the original
+		printer, ok := result.Entity.(Printer)
+		if !ok {
+			t.Fatalf("%s: entity is %T, want Printer", tt.name, result.Entity)
+		}
+		if printer.ID != tt.wantID {
+			t.Errorf("%s: got %s, want %s", tt.name, printer.ID, tt.wantID)
+		}
+	}
+
+	if len(f.idempotency) != 1 || len(f.idempotencyOrder) != 1 {
+		t.Errorf("%d keys remembered, want 1", len(f.idempotency))
+	}
+}
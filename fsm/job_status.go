@@ -0,0 +1,48 @@
+package fsm
+
+// Print job statuses
+const (
+	JobQueued    = "queued"
+	JobPrinting  = "printing"
+	JobPaused    = "paused"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// jobTransitions lists the statuses each job status may move to. Completed,
+// failed and cancelled jobs are final.
+var jobTransitions = map[string][]string{
+	JobQueued:    {JobPrinting, JobCancelled},
+	JobPrinting:  {JobPaused, JobCompleted, JobFailed, JobCancelled},
+	JobPaused:    {JobPrinting, JobFailed, JobCancelled},
+	JobCompleted: {},
+	JobFailed:    {},
+	JobCancelled: {},
+}
+
+// validJobStatus reports whether status is a known job status
+func validJobStatus(status string) bool {
+	_, ok := jobTransitions[status]
+	return ok
+}
+
+// canTransitionJob reports whether a job may move from one status to another
+func canTransitionJob(from, to string) bool {
+	for _, next := range jobTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// jobFinished reports whether a job status is final, freeing its printer
+func jobFinished(status string) bool {
+	return status == JobCompleted || status == JobFailed || status == JobCancelled
+}
+
+// JobStatuses returns every job status in lifecycle order
+func JobStatuses() []string {
+	return []string{JobQueued, JobPrinting, JobPaused, JobCompleted, JobFailed, JobCancelled}
+}
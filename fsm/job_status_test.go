@@ -0,0 +1,66 @@
+package fsm
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestJobTransitions(t *testing.T) {
+	// Each path starts from a job printing on printer-1
+	tests := []struct {
+		name    string
+		path    []string
+		wantErr error
+	}{
+		{"complete", []string{JobCompleted}, nil},
+		{"pause and resume", []string{JobPaused, JobPrinting, JobCompleted}, nil},
+		{"fail while paused", []string{JobPaused, JobFailed}, nil},
+		{"cancel", []string{JobCancelled}, nil},
+		{"back to queued", []string{JobQueued}, ErrConflict},
+		{"reopen completed", []string{JobCompleted, JobPrinting}, ErrConflict},
+		{"complete paused", []string{JobPaused, JobCompleted}, ErrConflict},
+		{"unknown status", []string{"melted"}, ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newLoadedFSM(t)
+			mustApply(t, f, Command{Type: CommandSubmitJob, Job: &PrintJob{PrinterID: "printer-1", FilamentWeight: 10}})
+
+			var err error
+			for _, status := range tt.path {
+				err = applyAt(t, f, Command{Type: CommandUpdateJobStatus, JobID: "job-1", Status: status}, time.Time{}).Err
+				if err != nil {
+					break
+				}
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLegacyJobStatusReplay(t *testing.T) {
+	// The sequence the legacy handlers wrote for a job run to completion
+	f := New()
+	for _, entry := range []string{
+		`{"type":"create_printer","printer":{"id":"p1","name":"Old","status":"idle"}}`,
+		`{"type":"submit_job","job":{"id":"job-1","status":"queued","printer_id":"p1","filament_weight":5}}`,
+		`{"type":"update_printer_status","printer_id":"p1","status":"printing","job_id":"job-1"}`,
+		`{"type":"update_job_status","job_id":"job-1","status":"completed"}`,
+	} {
+		if result := applyData(t, f, []byte(entry), time.Time{}); result.Err != nil {
+			t.Fatalf("%s: %v", entry, result.Err)
+		}
+	}
+
+	if job, _ := f.Job("job-1"); job.Status != JobCompleted {
+		t.Errorf("job is %s, want completed", job.Status)
+	}
+	if printer, _ := f.Printer("p1"); printer.Status != "idle" || printer.CurrentJobID != "" {
+		t.Errorf("printer is %s running %q, want idle", printer.Status, printer.CurrentJobID)
+	}
+}
@@ -0,0 +1,43 @@
+package fsm
+
+// Printer represents a 3D printer in the system
+type Printer struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Status       string `json:"status"` // "idle", "printing", "error"
+	FilamentID   string `json:"filament_id,omitempty"`
+	CurrentJobID string `json:"current_job_id,omitempty"`
+
+	// Queue holds the IDs of jobs waiting for this printer, oldest first
+	Queue []string `json:"queue,omitempty"`
+
+	BuildVolume  *BuildVolume `json:"build_volume,omitempty"`
+	Tags         []string     `json:"tags,omitempty"`
+	JobsAssigned int          `json:"jobs_assigned"`
+
+	// ModifyIndex is the index of the log entry that last changed the printer
+	ModifyIndex uint64 `json:"modify_index"`
+}
+
+// BuildVolume is a printable volume in millimetres
+type BuildVolume struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	Z float64 `json:"z"`
+}
+
+// Fits reports whether a part of size need fits in the volume. A nil volume
+// is unknown and fits nothing.
+func (v *BuildVolume) Fits(need BuildVolume) bool {
+	return v != nil && v.X >= need.X && v.Y >= need.Y && v.Z >= need.Z
+}
+
+// HasTag reports whether the printer carries tag
+func (p Printer) HasTag(tag string) bool {
+	for _, t := range p.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package fsm
+
+import (
+	"maps"
+	"slices"
+	"sort"
+)
+
+// Jobs returns a copy of every print job
+func (f *FSM) Jobs() map[string]PrintJob {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return maps.Clone(f.jobs)
+}
+
+// Job returns the print job with the given ID
+func (f *FSM) Job(id string) (PrintJob, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	job, exists := f.jobs[id]
+	return job, exists
+}
+
+// Printers returns a copy of every printer
+func (f *FSM) Printers() map[string]Printer {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.clonePrinters()
+}
+
+// Printer returns the printer with the given ID
+func (f *FSM) Printer(id string) (Printer, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	printer, exists := f.printers[id]
+	printer.Queue = slices.Clone(printer.Queue)
+	return printer, exists
+}
+
+// Filaments returns a copy of every filament
+func (f *FSM) Filaments() map[string]Filament {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return maps.Clone(f.filaments)
+}
+
+// Filament returns the filament with the given ID
+func (f *FSM) Filament(id string) (Filament, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	filament, exists := f.filaments[id]
+	return filament, exists
+}
+
+// Node returns the metadata registered for a Raft server ID
+func (f *FSM) Node(id string) (NodeMeta, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	node, exists := f.nodes[id]
+	return node, exists
+}
+
+// Counts is the number of each kind of entity in the FSM
+type Counts struct {
+	Jobs       int `json:"jobs"`
+	Printers   int `json:"printers"`
+	Filaments  int `json:"filaments"`
+	Nodes      int `json:"nodes"`
+	Unassigned int `json:"unassigned_jobs"`
+}
+
+// Counts returns the number of entities of each kind
+func (f *FSM) Counts() Counts {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return Counts{
+		Jobs:       len(f.jobs),
+		Printers:   len(f.printers),
+		Filaments:  len(f.filaments),
+		Nodes:      len(f.nodes),
+		Unassigned: len(f.unassigned),
+	}
+}
+
+// UnassignedJobs returns the jobs in the unassigned pool, oldest first
+func (f *FSM) UnassignedJobs() []PrintJob {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	jobs := make([]PrintJob, 0, len(f.unassigned))
+	for _, jobID := range f.unassigned {
+		if job, exists := f.jobs[jobID]; exists {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs
+}
+
+// Candidate is a printer the scheduler may assign a job to, with the
+// filament left on its spool after reservations
+type Candidate struct {
+	Printer   Printer
+	Available float64
+}
+
+// CompatiblePrinters returns the idle printers, sorted by ID, that the FSM
+// would accept for job
+func (f *FSM) CompatiblePrinters(job PrintJob, taken map[string]bool) []Candidate {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	var candidates []Candidate
+	for _, printer := range f.printers {
+		if printer.Status != "idle" || taken[printer.ID] {
+			continue
+		}
+		if f.checkPrinterFor(job, printer) != nil {
+			continue
+		}
+
+		filament := f.filaments[printer.FilamentID]
+		candidates = append(candidates, Candidate{
+			Printer:   printer,
+			Available: filament.RemainingWeight - f.reservedFilament(printer),
+		})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Printer.ID < candidates[j].Printer.ID
+	})
+	return candidates
+}
+
+// clonePrinters copies the printers map; the caller holds f.mu
+func (f *FSM) clonePrinters() map[string]Printer {
+	printers := make(map[string]Printer, len(f.printers))
+	for id, printer := range f.printers {
+		// The queue is the only slice the FSM appends to
+		printer.Queue = slices.Clone(printer.Queue)
+		printers[id] = printer
+	}
+	return printers
+}
@@ -0,0 +1,54 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds of command rejection, used by callers to choose how to report it
+var (
+	ErrNotFound = errors.New("not found")
+	ErrConflict = errors.New("conflict")
+	ErrInvalid  = errors.New("invalid")
+
+	// ErrPreconditionFailed rejects a write made against an outdated
+	// version of a job or printer
+	ErrPreconditionFailed = errors.New("precondition failed")
+)
+
+// ApplyResult is returned by FSM.Apply for every command. Entity holds the
+// created or updated object; Err is set if the FSM rejected the command.
+type ApplyResult struct {
+	Entity interface{}
+	Err    error
+}
+
+// FSMError is a rejection with a client-facing message, wrapping one of the
+// error kinds above
+type FSMError struct {
+	Kind    error
+	Message string
+}
+
+func (e *FSMError) Error() string { return e.Message }
+
+func (e *FSMError) Unwrap() error { return e.Kind }
+
+// fsmError builds an FSMError of the given kind
+func fsmError(kind error, format string, args ...interface{}) error {
+	return &FSMError{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
+
+// checkModifyIndex rejects a command whose compare-and-set index, if it has
+// one, is not the entity's current ModifyIndex
+func checkModifyIndex(cmd Command, entity, id string, current uint64) error {
+	if cmd.CASIndex != nil && *cmd.CASIndex != current {
+		return fsmError(ErrPreconditionFailed, "%s %s was modified at index %d, not %d", entity, id, current, *cmd.CASIndex)
+	}
+	return nil
+}
+
+// rejected builds the result for a command the FSM refused to apply
+func rejected(kind error, format string, args ...interface{}) ApplyResult {
+	return ApplyResult{Err: fsmError(kind, format, args...)}
+}
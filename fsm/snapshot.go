@@ -0,0 +1,405 @@
+package fsm
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/hashicorp/raft"
+)
+
+// Snapshots are written as a magic string and format version, followed by a
+// gzip stream of framed records:
+//
+//	kind (1 byte) | payload length (uvarint) | payload (JSON)
+//
+// The first record is a header with the entity counts, then one record per
+// entity, then a trailer holding the SHA-256 of every frame before it.
+// Persist and Restore handle one record at a time, so neither needs the whole
+// encoded state in memory. Snapshots in the older single JSON document
+// format, with or without a checksum line, are still restored.
+const snapshotMagic = "R3DSNAP"
+
+// New snapshots are written in snapshotFormatVersion. Version 3 added
+// idempotency key records; version 2 snapshots, without them, still restore.
+const (
+	snapshotFormatVersion    = 3
+	minSnapshotFormatVersion = 2
+)
+
+// Record kinds
+const (
+	recordHeader      byte = 'H'
+	recordCounters    byte = 'C'
+	recordNode        byte = 'N'
+	recordFilament    byte = 'F'
+	recordPrinter     byte = 'P'
+	recordJob         byte = 'J'
+	recordUnassigned  byte = 'U' // one job ID, in pool order
+	recordIdempotency byte = 'I' // one idempotency key, oldest first
+	recordTrailer     byte = 'Z'
+)
+
+// maxRecordSize bounds a single record so a corrupt length can't exhaust memory
+const maxRecordSize = 64 << 20
+
+// snapshotHeader is the first record of a snapshot
+type snapshotHeader struct {
+	Version    int `json:"version"`
+	Jobs       int `json:"jobs"`
+	Printers   int `json:"printers"`
+	Filaments  int `json:"filaments"`
+	Nodes      int `json:"nodes"`
+	Unassigned int `json:"unassigned"`
+	// Missing from snapshots taken before idempotency keys
+	IdempotencyKeys int `json:"idempotency_keys,omitempty"`
+}
+
+// snapshotChecksumPrefix starts the trailer line that older snapshots append
+// after the JSON state, holding the hex SHA-256 of the JSON
+const snapshotChecksumPrefix = "sha256:"
+
+// Snapshot implements the raft.FSMSnapshot interface. It owns its maps, so
+// Persist never races with FSM.Apply. Restore builds one too, so the FSM only
+// adopts the state once the whole snapshot has been read and verified.
+type Snapshot struct {
+	jobs       map[string]PrintJob
+	printers   map[string]Printer
+	filaments  map[string]Filament
+	nodes      map[string]NodeMeta
+	unassigned []string
+	counters   map[string]uint64
+
+	idempotency []*idempotencyRecord
+}
+
+// Persist streams the snapshot to the given sink
+func (s *Snapshot) Persist(sink raft.SnapshotSink) error {
+	if err := s.write(sink); err != nil {
+		sink.Cancel()
+		return err
+	}
+
+	return sink.Close()
+}
+
+func (s *Snapshot) write(w io.Writer) error {
+	if _, err := w.Write(append([]byte(snapshotMagic), snapshotFormatVersion)); err != nil {
+		return err
+	}
+
+	buffered := bufio.NewWriter(w)
+	compressed := gzip.NewWriter(buffered)
+	frames := &frameWriter{w: compressed, sum: sha256.New()}
+
+	frames.write(recordHeader, snapshotHeader{
+		Version:         snapshotFormatVersion,
+		Jobs:            len(s.jobs),
+		Printers:        len(s.printers),
+		Filaments:       len(s.filaments),
+		Nodes:           len(s.nodes),
+		Unassigned:      len(s.unassigned),
+		IdempotencyKeys: len(s.idempotency),
+	})
+	frames.write(recordCounters, s.counters)
+	for _, node := range s.nodes {
+		frames.write(recordNode, node)
+	}
+	for _, filament := range s.filaments {
+		frames.write(recordFilament, filament)
+	}
+	for _, printer := range s.printers {
+		frames.write(recordPrinter, printer)
+	}
+	for _, job := range s.jobs {
+		frames.write(recordJob, job)
+	}
+	for _, jobID := range s.unassigned {
+		frames.write(recordUnassigned, jobID)
+	}
+	for _, record := range s.idempotency {
+		frames.write(recordIdempotency, record)
+	}
+	frames.writeTrailer()
+
+	if frames.err != nil {
+		return frames.err
+	}
+	if err := compressed.Close(); err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
+
+// frameWriter writes records and hashes every frame it writes. The first
+// error sticks and turns later writes into no-ops.
+type frameWriter struct {
+	w   io.Writer
+	sum hash.Hash
+	err error
+}
+
+func (fw *frameWriter) write(kind byte, v interface{}) {
+	if fw.err != nil {
+		return
+	}
+
+	payload, err := json.Marshal(v)
+	if err != nil {
+		fw.err = err
+		return
+	}
+
+	fw.writeFrame(kind, payload, true)
+}
+
+func (fw *frameWriter) writeTrailer() {
+	if fw.err != nil {
+		return
+	}
+	fw.writeFrame(recordTrailer, []byte(hex.EncodeToString(fw.sum.Sum(nil))), false)
+}
+
+func (fw *frameWriter) writeFrame(kind byte, payload []byte, hashed bool) {
+	var prefix [1 + binary.MaxVarintLen64]byte
+	prefix[0] = kind
+	n := 1 + binary.PutUvarint(prefix[1:], uint64(len(payload)))
+
+	if hashed {
+		fw.sum.Write(prefix[:n])
+		fw.sum.Write(payload)
+	}
+
+	if _, err := fw.w.Write(prefix[:n]); err != nil {
+		fw.err = err
+		return
+	}
+	if _, err := fw.w.Write(payload); err != nil {
+		fw.err = err
+	}
+}
+
+// readSnapshot decodes a snapshot in either the framed or the older JSON format
+func readSnapshot(r io.Reader) (*Snapshot, error) {
+	buffered := bufio.NewReader(r)
+
+	magic, err := buffered.Peek(len(snapshotMagic) + 1)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if !bytes.HasPrefix(magic, []byte(snapshotMagic)) {
+		return readLegacySnapshot(buffered)
+	}
+
+	if version := magic[len(snapshotMagic)]; version < minSnapshotFormatVersion || version > snapshotFormatVersion {
+		return nil, fmt.Errorf("unsupported snapshot format version %d", version)
+	}
+	buffered.Discard(len(magic))
+
+	compressed, err := gzip.NewReader(buffered)
+	if err != nil {
+		return nil, err
+	}
+	defer compressed.Close()
+
+	return readFrames(bufio.NewReader(compressed))
+}
+
+func readFrames(r *bufio.Reader) (*Snapshot, error) {
+	sum := sha256.New()
+
+	kind, payload, err := readFrame(r, sum)
+	if err != nil {
+		return nil, err
+	}
+	if kind != recordHeader {
+		return nil, fmt.Errorf("snapshot does not start with a header record")
+	}
+
+	var header snapshotHeader
+	if err := json.Unmarshal(payload, &header); err != nil {
+		return nil, fmt.Errorf("invalid snapshot header: %w", err)
+	}
+
+	s := &Snapshot{
+		jobs:        make(map[string]PrintJob, header.Jobs),
+		printers:    make(map[string]Printer, header.Printers),
+		filaments:   make(map[string]Filament, header.Filaments),
+		nodes:       make(map[string]NodeMeta, header.Nodes),
+		unassigned:  make([]string, 0, header.Unassigned),
+		counters:    make(map[string]uint64),
+		idempotency: make([]*idempotencyRecord, 0, header.IdempotencyKeys),
+	}
+
+	for {
+		// The trailer's checksum covers every frame before it
+		computed := hex.EncodeToString(sum.Sum(nil))
+
+		kind, payload, err := readFrame(r, sum)
+		if err != nil {
+			return nil, err
+		}
+
+		switch kind {
+		case recordTrailer:
+			if want := string(payload); want != computed {
+				return nil, fmt.Errorf("snapshot checksum mismatch: expected %s, got %s", want, computed)
+			}
+			if len(s.jobs) != header.Jobs || len(s.printers) != header.Printers || len(s.filaments) != header.Filaments ||
+				len(s.nodes) != header.Nodes || len(s.unassigned) != header.Unassigned ||
+				len(s.idempotency) != header.IdempotencyKeys {
+				return nil, fmt.Errorf("snapshot record counts do not match its header")
+			}
+			return s, nil
+		case recordCounters:
+			err = json.Unmarshal(payload, &s.counters)
+		case recordNode:
+			var node NodeMeta
+			err = json.Unmarshal(payload, &node)
+			s.nodes[node.ID] = node
+		case recordFilament:
+			var filament Filament
+			err = json.Unmarshal(payload, &filament)
+			s.filaments[filament.ID] = filament
+		case recordPrinter:
+			var printer Printer
+			err = json.Unmarshal(payload, &printer)
+			s.printers[printer.ID] = printer
+		case recordJob:
+			var job PrintJob
+			err = json.Unmarshal(payload, &job)
+			s.jobs[job.ID] = job
+		case recordUnassigned:
+			var jobID string
+			err = json.Unmarshal(payload, &jobID)
+			s.unassigned = append(s.unassigned, jobID)
+		case recordIdempotency:
+			record := &idempotencyRecord{}
+			err = json.Unmarshal(payload, record)
+			s.idempotency = append(s.idempotency, record)
+		default:
+			err = fmt.Errorf("unknown record kind %q", kind)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid snapshot record: %w", err)
+		}
+	}
+}
+
+// readFrame reads one record, adding it to sum unless it is the trailer
+func readFrame(r *bufio.Reader, sum hash.Hash) (byte, []byte, error) {
+	kind, err := r.ReadByte()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return 0, nil, fmt.Errorf("snapshot ended without a trailer")
+		}
+		return 0, nil, err
+	}
+
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if size > maxRecordSize {
+		return 0, nil, fmt.Errorf("snapshot record of %d bytes exceeds limit", size)
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+
+	if kind != recordTrailer {
+		var prefix [1 + binary.MaxVarintLen64]byte
+		prefix[0] = kind
+		n := 1 + binary.PutUvarint(prefix[1:], size)
+		sum.Write(prefix[:n])
+		sum.Write(payload)
+	}
+
+	return kind, payload, nil
+}
+
+// legacySnapshot is the single JSON document written by older versions.
+// Sections added over time are missing from older snapshots.
+type legacySnapshot struct {
+	Jobs       map[string]PrintJob `json:"jobs"`
+	Printers   map[string]Printer  `json:"printers"`
+	Filaments  map[string]Filament `json:"filaments"`
+	Nodes      map[string]NodeMeta `json:"nodes"`
+	Unassigned []string            `json:"unassigned"`
+	Counters   map[string]uint64   `json:"counters"`
+}
+
+func readLegacySnapshot(r io.Reader) (*Snapshot, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := verifyLegacySnapshot(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var data legacySnapshot
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, err
+	}
+
+	s := &Snapshot{
+		jobs:       data.Jobs,
+		printers:   data.Printers,
+		filaments:  data.Filaments,
+		nodes:      data.Nodes,
+		unassigned: data.Unassigned,
+		counters:   data.Counters,
+	}
+	if s.jobs == nil {
+		s.jobs = make(map[string]PrintJob)
+	}
+	if s.printers == nil {
+		s.printers = make(map[string]Printer)
+	}
+	if s.filaments == nil {
+		s.filaments = make(map[string]Filament)
+	}
+	if s.nodes == nil {
+		s.nodes = make(map[string]NodeMeta)
+	}
+
+	// counters stays nil when absent so FSM.Restore rebuilds it
+	return s, nil
+}
+
+// verifyLegacySnapshot checks the checksum line that older snapshots end with
+// and returns the JSON state before it. Snapshots from before checksums were
+// added have no such line and are returned unchanged.
+func verifyLegacySnapshot(raw []byte) ([]byte, error) {
+	trimmed := bytes.TrimRight(raw, "\n")
+	i := bytes.LastIndexByte(trimmed, '\n')
+	if i < 0 || !bytes.HasPrefix(trimmed[i+1:], []byte(snapshotChecksumPrefix)) {
+		return raw, nil
+	}
+
+	body := trimmed[:i]
+	want := string(trimmed[i+1+len(snapshotChecksumPrefix):])
+
+	sum := sha256.Sum256(body)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return nil, fmt.Errorf("snapshot checksum mismatch: expected %s, got %s", want, got)
+	}
+
+	return body, nil
+}
+
+// Release is called when we're done with the snapshot
+func (s *Snapshot) Release() {}
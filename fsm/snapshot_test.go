@@ -0,0 +1,169 @@
+package fsm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func snapshotBytes(t *testing.T, f *FSM) []byte {
+	t.Helper()
+
+	snapshot, err := f.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := snapshot.(*Snapshot).write(&buf); err != nil {
+		t.Fatalf("write snapshot: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func restore(data []byte) (*FSM, error) {
+	f := New()
+	return f, f.Restore(io.NopCloser(bytes.NewReader(data)))
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	f := newLoadedFSM(t)
+	mustApply(t, f, Command{Type: CommandSubmitJob, Job: &PrintJob{PrinterID: "printer-1", FilamentWeight: 10}})
+	mustApply(t, f, Command{Type: CommandSubmitJob, Job: &PrintJob{PrinterID: "printer-1", FilamentWeight: 20}})
+	mustApply(t, f, Command{Type: CommandSubmitJob, Job: &PrintJob{FilamentWeight: 5}})
+	mustApply(t, f, Command{Type: CommandRegisterNode, Node: &NodeMeta{ID: "n1", HTTPAddr: "127.0.0.1:8080"}})
+	if result := applyAt(t, f, Command{Type: CommandCreatePrinter, Printer: &Printer{Name: "B"}, IdempotencyKey: "k1"}, time.Unix(1000, 0).UTC()); result.Err != nil {
+		t.Fatalf("create printer: %v", result.Err)
+	}
+
+	restored, err := restore(snapshotBytes(t, f))
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+
+	checks := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"jobs", restored.jobs, f.jobs},
+		{"printers", restored.printers, f.printers},
+		{"filaments", restored.filaments, f.filaments},
+		{"nodes", restored.nodes, f.nodes},
+		{"unassigned", restored.unassigned, f.unassigned},
+		{"counters", restored.counters, f.counters},
+		{"idempotency", restored.idempotency, f.idempotency},
+	}
+	for _, c := range checks {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s: restored %+v, want %+v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestReadSnapshotFormats(t *testing.T) {
+	f := newLoadedFSM(t)
+	framed := snapshotBytes(t, f)
+
+	// Version 2 snapshots are the same records without idempotency keys
+	version2 := bytes.Clone(framed)
+	version2[len(snapshotMagic)] = 2
+
+	unsupported := bytes.Clone(framed)
+	unsupported[len(snapshotMagic)] = snapshotFormatVersion + 1
+
+	// A record changed after the trailer was written
+	corrupt := recompress(t, framed, func(records []byte) []byte {
+		return bytes.Replace(records, []byte(`"PLA"`), []byte(`"ABS"`), 1)
+	})
+
+	legacy := `{"jobs":{"job-3":{"id":"job-3","status":"queued","printer_id":"p1","filament_weight":5}},` +
+		`"printers":{"p1":{"id":"p1","name":"Old","status":"idle","filament_weight":500}}}`
+	sum := sha256.Sum256([]byte(legacy))
+	checksummed := legacy + "\n" + snapshotChecksumPrefix + hex.EncodeToString(sum[:]) + "\n"
+	badChecksum := legacy + "\n" + snapshotChecksumPrefix + strings.Repeat("0", 64) + "\n"
+
+	tests := []struct {
+		name     string
+		data     []byte
+		printers []string
+		jobs     []string
+		counters map[string]uint64
+		wantErr  string
+	}{
+		{name: "framed", data: framed, printers: []string{"printer-1"}, counters: map[string]uint64{"printer": 1, "filament": 1}},
+		{name: "framed version 2", data: version2, printers: []string{"printer-1"}, counters: map[string]uint64{"printer": 1, "filament": 1}},
+		{name: "unsupported version", data: unsupported, wantErr: "unsupported snapshot format version"},
+		{name: "record changed", data: corrupt, wantErr: "checksum mismatch"},
+		{name: "legacy JSON", data: []byte(legacy), printers: []string{"p1"}, jobs: []string{"job-3"}, counters: map[string]uint64{"job": 3}},
+		{name: "legacy JSON with checksum", data: []byte(checksummed), printers: []string{"p1"}, jobs: []string{"job-3"}, counters: map[string]uint64{"job": 3}},
+		{name: "legacy checksum mismatch", data: []byte(badChecksum), wantErr: "checksum mismatch"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restored, err := restore(tt.data)
+			if tt.printers == nil {
+				if err == nil {
+					t.Fatalf("Restore succeeded, want an error")
+				}
+				if !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Restore error %q, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Restore: %v", err)
+			}
+
+			if got := sortedKeys(restored.printers); !reflect.DeepEqual(got, tt.printers) {
+				t.Errorf("printers %v, want %v", got, tt.printers)
+			}
+			if got := sortedKeys(restored.jobs); !reflect.DeepEqual(got, tt.jobs) {
+				t.Errorf("jobs %v, want %v", got, tt.jobs)
+			}
+			for kind, want := range tt.counters {
+				if got := restored.counters[kind]; got != want {
+					t.Errorf("%s counter %d, want %d", kind, got, want)
+				}
+			}
+		})
+	}
+}
+
+// recompress rewrites the records of a framed snapshot
+func recompress(t *testing.T, snapshot []byte, edit func([]byte) []byte) []byte {
+	t.Helper()
+
+	prefix := len(snapshotMagic) + 1
+	reader, err := gzip.NewReader(bytes.NewReader(snapshot[prefix:]))
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	records, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+
+	var buf bytes.Buffer
+	buf.Write(snapshot[:prefix])
+	writer := gzip.NewWriter(&buf)
+	writer.Write(edit(records))
+	writer.Close()
+	return buf.Bytes()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
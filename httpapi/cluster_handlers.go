@@ -0,0 +1,321 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"raft3d/store"
+)
+
+// Join retry policy: the delay doubles after every round through the seeds,
+// up to joinMaxBackoff
+const (
+	joinAttempts       = 8
+	joinInitialBackoff = 500 * time.Millisecond
+	joinMaxBackoff     = 10 * time.Second
+	joinTimeout        = 10 * time.Second
+)
+
+// registerInterval is how often RegisterWithLeader checks whether this node's
+// HTTP address has been replicated yet
+const registerInterval = time.Second
+
+// /join handler. Followers forward it to the leader like any other write.
+func (s *Server) handleJoin(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	addr := r.URL.Query().Get("addr")
+
+	if id == "" || addr == "" {
+		writeError(w, "Missing id or addr", http.StatusBadRequest)
+		return
+	}
+
+	// Reporting nodes join with nonvoter=true to replicate without voting
+	voter := r.URL.Query().Get("nonvoter") != "true"
+
+	if err := s.store.Join(id, addr, r.URL.Query().Get("http"), voter); err != nil {
+		writeError(w, err.Error(), clusterErrorStatus(err))
+		return
+	}
+
+	fmt.Fprintf(w, "Node %s at %s joined successfully\n", id, addr)
+}
+
+// clusterErrorStatus maps a membership change error to an HTTP status, telling
+// a joining node whether retrying can help
+func clusterErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, store.ErrUnknownMember):
+		return http.StatusNotFound
+	case errors.Is(err, store.ErrAddressInUse):
+		return http.StatusConflict
+	default:
+		return errorStatus(err)
+	}
+}
+
+// MemberRequest adds a server to the cluster
+type MemberRequest struct {
+	ID       string `json:"id"`
+	Address  string `json:"address"`
+	HTTPAddr string `json:"http_addr,omitempty"`
+	Suffrage string `json:"suffrage,omitempty"` // "voter" (default) or "nonvoter"
+}
+
+// SuffrageRequest promotes or demotes a member
+type SuffrageRequest struct {
+	Suffrage string `json:"suffrage"`
+}
+
+func (s *Server) getMembersHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.prepareRead(w, r) {
+		return
+	}
+
+	members, err := s.store.Members()
+	if err != nil {
+		writeError(w, err.Error(), clusterErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(members)
+}
+
+func (s *Server) addMemberHandler(w http.ResponseWriter, r *http.Request) {
+	var memberReq MemberRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&memberReq); err != nil {
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if memberReq.ID == "" || memberReq.Address == "" {
+		writeError(w, "Member id and address are required", http.StatusBadRequest)
+		return
+	}
+
+	voter, ok := parseSuffrage(memberReq.Suffrage)
+	if !ok {
+		writeError(w, "Suffrage must be \"voter\" or \"nonvoter\"", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.store.Join(memberReq.ID, memberReq.Address, memberReq.HTTPAddr, voter); err != nil {
+		writeError(w, err.Error(), clusterErrorStatus(err))
+		return
+	}
+
+	s.writeMember(w, memberReq.ID)
+}
+
+func (s *Server) updateMemberHandler(w http.ResponseWriter, r *http.Request) {
+	// Extract member ID from URL path
+	id := r.URL.Path[len("/cluster/members/"):]
+
+	var suffrageReq SuffrageRequest
+	if err := json.NewDecoder(r.Body).Decode(&suffrageReq); err != nil {
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	voter, ok := parseSuffrage(suffrageReq.Suffrage)
+	if !ok || suffrageReq.Suffrage == "" {
+		writeError(w, "Suffrage must be \"voter\" or \"nonvoter\"", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.store.SetVoter(id, voter); err != nil {
+		writeError(w, err.Error(), clusterErrorStatus(err))
+		return
+	}
+
+	s.writeMember(w, id)
+}
+
+func (s *Server) removeMemberHandler(w http.ResponseWriter, r *http.Request) {
+	// Extract member ID from URL path
+	id := r.URL.Path[len("/cluster/members/"):]
+
+	if err := s.store.RemoveMember(id); err != nil {
+		writeError(w, err.Error(), clusterErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeMember responds with the current view of one member
+func (s *Server) writeMember(w http.ResponseWriter, id string) {
+	members, err := s.store.Members()
+	if err != nil {
+		writeError(w, err.Error(), clusterErrorStatus(err))
+		return
+	}
+
+	for _, member := range members {
+		if member.ID == id {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(member)
+			return
+		}
+	}
+
+	writeError(w, "Member not found", http.StatusNotFound)
+}
+
+// transferLeadershipHandler hands leadership to the member named by ?to=, or
+// to whichever voter is most up to date, and reports the new leader
+func (s *Server) transferLeadershipHandler(w http.ResponseWriter, r *http.Request) {
+	leaderID, err := s.store.TransferLeadership(r.URL.Query().Get("to"))
+	if err != nil {
+		writeError(w, err.Error(), clusterErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"leader_id": string(leaderID)})
+}
+
+// parseSuffrage reports whether suffrage asks for a voter, defaulting to yes
+func parseSuffrage(suffrage string) (voter bool, ok bool) {
+	switch suffrage {
+	case "", "voter":
+		return true, true
+	case "nonvoter":
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// Join asks the cluster to add this node, trying each seed address in turn
+// and retrying with backoff until one accepts. Seeds may be any member:
+// followers forward the request to the leader, or redirect to it.
+func Join(seeds []string, id, raftAddr, httpAddr string, voter bool) error {
+	if len(seeds) == 0 {
+		return errors.New("no seed addresses to join")
+	}
+
+	client := &http.Client{Timeout: joinTimeout}
+	query := url.Values{"id": {id}, "addr": {raftAddr}, "http": {httpAddr}}
+	if !voter {
+		query.Set("nonvoter", "true")
+	}
+
+	backoff := joinInitialBackoff
+	var lastErr error
+	for attempt := 1; attempt <= joinAttempts; attempt++ {
+		for _, seed := range seeds {
+			retry, err := requestJoin(client, fmt.Sprintf("http://%s/join?%s", seed, query.Encode()))
+			if err == nil {
+				log.Printf("Joined cluster through %s", seed)
+				return nil
+			}
+			if !retry {
+				return fmt.Errorf("join rejected by %s: %w", seed, err)
+			}
+
+			log.Printf("Join through %s failed (attempt %d/%d): %v", seed, attempt, joinAttempts, err)
+			lastErr = err
+		}
+
+		if attempt < joinAttempts {
+			time.Sleep(backoff)
+			backoff = min(2*backoff, joinMaxBackoff)
+		}
+	}
+
+	return fmt.Errorf("giving up after %d attempts: %w", joinAttempts, lastErr)
+}
+
+// requestJoin sends one join request, following redirects to the leader. It
+// reports whether a failure is worth retrying.
+func requestJoin(client *http.Client, joinURL string) (bool, error) {
+	resp, err := client.Post(joinURL, "", nil)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return false, nil
+	}
+
+	var body struct {
+		Error string `json:"error"`
+	}
+	json.NewDecoder(resp.Body).Decode(&body)
+
+	err = fmt.Errorf("%s: %s", resp.Status, body.Error)
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	return retry, err
+}
+
+// RegisterWithLeader replicates this node's HTTP address for nodes that were
+// bootstrapped from -peers and never sent a /join, so the leader can redirect
+// and forward to them if they take over. Once a leader is known it sends the
+// leader a /join for this node, which for an existing member only records
+// the address. It returns when the address has been replicated or ctx is
+// done.
+func RegisterWithLeader(ctx context.Context, st *store.Store, httpAddr string) {
+	client := &http.Client{Timeout: joinTimeout}
+	ticker := time.NewTicker(registerInterval)
+	defer ticker.Stop()
+
+	for {
+		if registerOnce(client, st, httpAddr) {
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// registerOnce makes one registration attempt and reports whether the address
+// is already replicated. The leader registers itself, and a node that is not
+// in the configuration has nothing to register.
+func registerOnce(client *http.Client, st *store.Store, httpAddr string) bool {
+	members, err := st.Members()
+	if err != nil {
+		return false
+	}
+	var self *store.Member
+	for i := range members {
+		if members[i].ID == st.ID() {
+			self = &members[i]
+		}
+	}
+	if self == nil {
+		return false
+	}
+	if self.HTTPAddr == httpAddr {
+		return true
+	}
+
+	leaderAddr, ok := st.LeaderHTTPAddr()
+	if !ok || st.IsLeader() {
+		return false
+	}
+
+	query := url.Values{"id": {self.ID}, "addr": {self.Address}, "http": {httpAddr}}
+	if self.Suffrage != "voter" {
+		query.Set("nonvoter", "true")
+	}
+	if _, err := requestJoin(client, fmt.Sprintf("http://%s/join?%s", leaderAddr, query.Encode())); err != nil {
+		log.Printf("Failed to register HTTP address with the leader: %v", err)
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRequestJoin(t *testing.T) {
+	tests := []struct {
+		status    int
+		wantErr   bool
+		wantRetry bool
+	}{
+		{status: http.StatusOK},
+		{status: http.StatusNoContent},
+		{status: http.StatusInternalServerError, wantErr: true, wantRetry: true},
+		{status: http.StatusServiceUnavailable, wantErr: true, wantRetry: true},
+		{status: http.StatusTooManyRequests, wantErr: true, wantRetry: true},
+		{status: http.StatusBadRequest, wantErr: true},
+		{status: http.StatusNotFound, wantErr: true},
+		{status: http.StatusConflict, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.status >= 300 {
+					writeError(w, "nope", tt.status)
+					return
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			retry, err := requestJoin(srv.Client(), srv.URL+"/join")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if retry != tt.wantRetry {
+				t.Errorf("got retry %v, want %v", retry, tt.wantRetry)
+			}
+			if err != nil && !strings.Contains(err.Error(), "nope") {
+				t.Errorf("error %q does not carry the server's message", err)
+			}
+		})
+	}
+}
+
+// joinServer answers /join with status and counts the requests it gets
+func joinServer(t *testing.T, status int, requests *atomic.Int32, handle func(*http.Request)) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if handle != nil {
+			handle(r)
+		}
+		if status >= 300 {
+			writeError(w, http.StatusText(status), status)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func hostOf(srv *httptest.Server) string {
+	return strings.TrimPrefix(srv.URL, "http://")
+}
+
+func TestJoin(t *testing.T) {
+	t.Run("sends the node's addresses", func(t *testing.T) {
+		var requests atomic.Int32
+		var query url.Values
+		srv := joinServer(t, http.StatusOK, &requests, func(r *http.Request) {
+			if r.Method != http.MethodPost || r.URL.Path != "/join" {
+				t.Errorf("got %s %s, want POST /join", r.Method, r.URL.Path)
+			}
+			query = r.URL.Query()
+		})
+
+		if err := Join([]string{hostOf(srv)}, "n4", "127.0.0.1:9004", "127.0.0.1:8084", false); err != nil {
+			t.Fatalf("Join: %v", err)
+		}
+		want := url.Values{"id": {"n4"}, "addr": {"127.0.0.1:9004"}, "http": {"127.0.0.1:8084"}, "nonvoter": {"true"}}
+		if query.Encode() != want.Encode() {
+			t.Errorf("got query %s, want %s", query.Encode(), want.Encode())
+		}
+	})
+
+	t.Run("follows a redirect to the leader", func(t *testing.T) {
+		var leaderRequests, followerRequests atomic.Int32
+		leader := joinServer(t, http.StatusOK, &leaderRequests, nil)
+		follower := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			followerRequests.Add(1)
+			http.Redirect(w, r, leader.URL+r.URL.RequestURI(), http.StatusTemporaryRedirect)
+		}))
+		defer follower.Close()
+
+		if err := Join([]string{hostOf(follower)}, "n4", "127.0.0.1:9004", "", true); err != nil {
+			t.Fatalf("Join: %v", err)
+		}
+		if followerRequests.Load() != 1 || leaderRequests.Load() != 1 {
+			t.Errorf("follower got %d requests and leader %d, want 1 each", followerRequests.Load(), leaderRequests.Load())
+		}
+	})
+
+	t.Run("moves on to the next seed after a 5xx", func(t *testing.T) {
+		var downRequests, upRequests atomic.Int32
+		down := joinServer(t, http.StatusServiceUnavailable, &downRequests, nil)
+		up := joinServer(t, http.StatusOK, &upRequests, nil)
+
+		if err := Join([]string{hostOf(down), hostOf(up)}, "n4", "127.0.0.1:9004", "", true); err != nil {
+			t.Fatalf("Join: %v", err)
+		}
+		if downRequests.Load() != 1 || upRequests.Load() != 1 {
+			t.Errorf("seeds got %d and %d requests, want 1 each", downRequests.Load(), upRequests.Load())
+		}
+	})
+
+	t.Run("stops on a 4xx", func(t *testing.T) {
+		var conflictRequests, upRequests atomic.Int32
+		conflict := joinServer(t, http.StatusConflict, &conflictRequests, nil)
+		up := joinServer(t, http.StatusOK, &upRequests, nil)
+
+		err := Join([]string{hostOf(conflict), hostOf(up)}, "n4", "127.0.0.1:9004", "", true)
+		if err == nil || !strings.Contains(err.Error(), "rejected") {
+			t.Fatalf("got %v, want a rejection", err)
+		}
+		if conflictRequests.Load() != 1 || upRequests.Load() != 0 {
+			t.Errorf("seeds got %d and %d requests, want 1 and 0", conflictRequests.Load(), upRequests.Load())
+		}
+	})
+
+	t.Run("needs a seed", func(t *testing.T) {
+		if err := Join(nil, "n4", "127.0.0.1:9004", "", true); err == nil {
+			t.Fatal("Join with no seeds succeeded")
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/hashicorp/raft"
+
+	"raft3d/fsm"
+	"raft3d/store"
+)
+
+// errorStatus maps an FSM or Raft error to the HTTP status returned to the
+// client. Errors from a node that is stepping down or shutting down are
+// 503s, which the client may retry elsewhere.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, fsm.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, fsm.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, fsm.ErrInvalid):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, fsm.ErrPreconditionFailed):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, store.ErrShuttingDown), errors.Is(err, raft.ErrRaftShutdown),
+		errors.Is(err, raft.ErrNotLeader), errors.Is(err, raft.ErrLeadershipLost),
+		errors.Is(err, raft.ErrLeadershipTransferInProgress):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeError sends a JSON error body, with the same argument order as http.Error
+func writeError(w http.ResponseWriter, message string, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// maxIdempotencyKeyLength bounds the Idempotency-Key header, which is kept
+// in the replicated state
+const maxIdempotencyKeyLength = 255
+
+// applyCommand submits a command on behalf of a handler, writing an error
+// response and returning false if the apply fails or the FSM rejects it. A
+// request with an Idempotency-Key header gets the result of the first
+// request with that key, so clients can safely retry writes that timed out.
+func (s *Server) applyCommand(w http.ResponseWriter, r *http.Request, command fsm.Command) (interface{}, bool) {
+	command.IdempotencyKey = r.Header.Get("Idempotency-Key")
+	if len(command.IdempotencyKey) > maxIdempotencyKeyLength {
+		writeError(w, "Idempotency-Key is too long", http.StatusBadRequest)
+		return nil, false
+	}
+
+	entity, err := s.store.Apply(command)
+	if err != nil {
+		writeError(w, err.Error(), errorStatus(err))
+		return nil, false
+	}
+
+	setEntityETag(w, entity)
+	return entity, true
+}
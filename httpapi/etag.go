@@ -0,0 +1,54 @@
+package httpapi
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"raft3d/fsm"
+)
+
+// Jobs and printers are versioned by their ModifyIndex, which is sent as a
+// strong ETag. Writes to them can be made conditional with If-Match or a
+// cas_index field, and the FSM rejects them with 412 if the entity has
+// changed since.
+
+// setETag reports the version of a job or printer
+func setETag(w http.ResponseWriter, modifyIndex uint64) {
+	w.Header().Set("ETag", strconv.Quote(strconv.FormatUint(modifyIndex, 10)))
+}
+
+// setEntityETag sets the ETag for the job or printer returned by a write
+func setEntityETag(w http.ResponseWriter, entity interface{}) {
+	switch e := entity.(type) {
+	case fsm.PrintJob:
+		setETag(w, e.ModifyIndex)
+	case fsm.Printer:
+		setETag(w, e.ModifyIndex)
+	}
+}
+
+// casIndex returns the version a write is conditional on, from If-Match or
+// the request body's cas_index, or nil for an unconditional write. Version 0
+// is a valid condition: it is the ETag of entities restored from snapshots
+// taken before versions were tracked. It writes an error response and
+// returns false if they are invalid or disagree.
+func casIndex(w http.ResponseWriter, r *http.Request, bodyIndex *uint64) (*uint64, bool) {
+	ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+	if ifMatch == "" || ifMatch == "*" {
+		return bodyIndex, true
+	}
+
+	index, err := strconv.ParseUint(strings.Trim(ifMatch, `"`), 10, 64)
+	if err != nil {
+		writeError(w, "If-Match must be an ETag returned by this API", http.StatusBadRequest)
+		return nil, false
+	}
+
+	if bodyIndex != nil && *bodyIndex != index {
+		writeError(w, "If-Match and cas_index disagree", http.StatusBadRequest)
+		return nil, false
+	}
+
+	return &index, true
+}
@@ -0,0 +1,67 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestCASIndex(t *testing.T) {
+	index := func(v uint64) *uint64 { return &v }
+
+	tests := []struct {
+		name      string
+		ifMatch   string
+		body      *uint64
+		want      *uint64
+		wantError bool
+	}{
+		{name: "unconditional"},
+		{name: "body only", body: index(7), want: index(7)},
+		{name: "wildcard", ifMatch: "*"},
+		{name: "wildcard with body", ifMatch: "*", body: index(7), want: index(7)},
+		{name: "quoted", ifMatch: `"7"`, want: index(7)},
+		{name: "unquoted", ifMatch: "7", want: index(7)},
+		{name: "spaces", ifMatch: ` "7" `, want: index(7)},
+		{name: "version 0", ifMatch: `"0"`, want: index(0)},
+		{name: "body version 0", body: index(0), want: index(0)},
+		{name: "agrees with body", ifMatch: `"7"`, body: index(7), want: index(7)},
+		{name: "disagrees with body", ifMatch: `"7"`, body: index(8), wantError: true},
+		{name: "disagrees with body version 0", ifMatch: `"0"`, body: index(7), wantError: true},
+		{name: "weak", ifMatch: `W/"7"`, wantError: true},
+		{name: "not a number", ifMatch: `"abc"`, wantError: true},
+		{name: "negative", ifMatch: `"-1"`, wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/printers/printer-1", nil)
+			if tt.ifMatch != "" {
+				r.Header.Set("If-Match", tt.ifMatch)
+			}
+			w := httptest.NewRecorder()
+
+			got, ok := casIndex(w, r, tt.body)
+			if tt.wantError {
+				if ok || w.Code != http.StatusBadRequest {
+					t.Fatalf("got ok %v and status %d, want a 400", ok, w.Code)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("rejected with %d: %s", w.Code, w.Body)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", deref(got), deref(tt.want))
+			}
+		})
+	}
+}
+
+func deref(index *uint64) interface{} {
+	if index == nil {
+		return nil
+	}
+	return *index
+}
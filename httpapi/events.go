@@ -0,0 +1,95 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"raft3d/store"
+)
+
+// eventKeepalive is how often an idle event stream sends a comment, so
+// proxies don't close it
+const eventKeepalive = 15 * time.Second
+
+// /events handler. Streams every applied command as a server-sent event whose
+// ID is its Raft log index. Any node can serve the stream: a client that
+// reconnects, to the same node or another, resumes after the index in
+// Last-Event-ID (or ?last_event_id=). If those events are gone, it gets a
+// "reset" event and should reload the state before applying further events.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	cursor := s.store.LastEventIndex()
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	if lastEventID != "" {
+		index, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			writeError(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		cursor = index
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepalive := time.NewTicker(eventKeepalive)
+	defer keepalive.Stop()
+
+	for {
+		events, wake, err := s.store.Events(cursor)
+		if errors.Is(err, store.ErrEventsTruncated) {
+			cursor = s.store.LastEventIndex()
+			writeEvent(w, "reset", cursor, map[string]uint64{"index": cursor})
+		}
+
+		for _, event := range events {
+			writeEvent(w, event.Command, event.Index, event)
+			cursor = event.Index
+		}
+		flusher.Flush()
+
+		select {
+		case <-wake:
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-s.closeStreams:
+			return
+		}
+	}
+}
+
+// writeEvent writes one server-sent event with a JSON payload
+func writeEvent(w http.ResponseWriter, name string, index uint64, data interface{}) {
+	payload, _ := json.Marshal(data)
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", index, name, payload)
+}
+
+// CloseStreams ends every open event stream and blocking read. Register it
+// with http.Server.RegisterOnShutdown, since Shutdown otherwise waits for
+// streams that never go idle.
+func (s *Server) CloseStreams() {
+	s.closeOnce.Do(func() { close(s.closeStreams) })
+}
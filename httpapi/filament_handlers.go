@@ -0,0 +1,190 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"raft3d/fsm"
+)
+
+// FilamentRequest represents the input to create a filament
+type FilamentRequest struct {
+	Type   string  `json:"type"`
+	Color  string  `json:"color"`
+	Weight float64 `json:"weight"`
+}
+
+// FilamentUpdateRequest represents the fields of a filament that can be changed
+type FilamentUpdateRequest struct {
+	Type            *string  `json:"type,omitempty"`
+	Color           *string  `json:"color,omitempty"`
+	RemainingWeight *float64 `json:"remaining_weight,omitempty"`
+}
+
+func (s *Server) createFilamentHandler(w http.ResponseWriter, r *http.Request) {
+	var filamentReq FilamentRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&filamentReq); err != nil {
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if filamentReq.Type == "" || filamentReq.Weight <= 0 {
+		writeError(w, "Filament type and a positive weight are required", http.StatusBadRequest)
+		return
+	}
+
+	// The FSM assigns the ID when the command is applied
+	filament := fsm.Filament{
+		Type:            filamentReq.Type,
+		Color:           filamentReq.Color,
+		TotalWeight:     filamentReq.Weight,
+		RemainingWeight: filamentReq.Weight,
+	}
+
+	// Create command
+	command := fsm.Command{
+		Type:     fsm.CommandCreateFilament,
+		Filament: &filament,
+	}
+
+	response, ok := s.applyCommand(w, r, command)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s *Server) getFilamentsHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.prepareRead(w, r) {
+		return
+	}
+
+	// Return all filaments
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.store.FSM().Filaments())
+}
+
+func (s *Server) getFilamentHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.prepareRead(w, r) {
+		return
+	}
+
+	// Extract filament ID from URL path
+	filamentID := r.URL.Path[len("/filaments/"):]
+
+	filament, exists := s.store.FSM().Filament(filamentID)
+	if !exists {
+		writeError(w, "Filament not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(filament)
+}
+
+func (s *Server) updateFilamentHandler(w http.ResponseWriter, r *http.Request) {
+	// Extract filament ID from URL path
+	filamentID := r.URL.Path[len("/filaments/"):]
+
+	var update FilamentUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Only send the fields the client asked to change
+	command := fsm.Command{
+		Type:            fsm.CommandUpdateFilament,
+		FilamentID:      filamentID,
+		FilamentType:    update.Type,
+		Color:           update.Color,
+		RemainingWeight: update.RemainingWeight,
+	}
+
+	filament, ok := s.applyCommand(w, r, command)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(filament)
+}
+
+func (s *Server) deleteFilamentHandler(w http.ResponseWriter, r *http.Request) {
+	// Extract filament ID from URL path
+	filamentID := r.URL.Path[len("/filaments/"):]
+
+	command := fsm.Command{
+		Type:       fsm.CommandDeleteFilament,
+		FilamentID: filamentID,
+	}
+
+	if _, ok := s.applyCommand(w, r, command); !ok {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) loadFilamentHandler(w http.ResponseWriter, r *http.Request) {
+	// Extract printer ID from URL path (/printers/<id>/filament)
+	printerID := strings.TrimSuffix(r.URL.Path[len("/printers/"):], "/filament")
+
+	var loadReq struct {
+		FilamentID string  `json:"filament_id"`
+		CASIndex   *uint64 `json:"cas_index,omitempty"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&loadReq); err != nil {
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	index, ok := casIndex(w, r, loadReq.CASIndex)
+	if !ok {
+		return
+	}
+
+	command := fsm.Command{
+		Type:       fsm.CommandLoadFilament,
+		PrinterID:  printerID,
+		FilamentID: loadReq.FilamentID,
+		CASIndex:   index,
+	}
+
+	printer, ok := s.applyCommand(w, r, command)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(printer)
+}
+
+func (s *Server) unloadFilamentHandler(w http.ResponseWriter, r *http.Request) {
+	// Extract printer ID from URL path (/printers/<id>/filament)
+	printerID := strings.TrimSuffix(r.URL.Path[len("/printers/"):], "/filament")
+
+	index, ok := casIndex(w, r, nil)
+	if !ok {
+		return
+	}
+
+	command := fsm.Command{
+		Type:      fsm.CommandUnloadFilament,
+		PrinterID: printerID,
+		CASIndex:  index,
+	}
+
+	printer, ok := s.applyCommand(w, r, command)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(printer)
+}
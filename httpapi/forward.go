@@ -0,0 +1,55 @@
+package httpapi
+
+import (
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+)
+
+// forwardedHeader marks a request that has already been forwarded once, so a
+// stale leader view on the receiving node can't bounce it around the cluster
+const forwardedHeader = "X-Raft3d-Forwarded-By"
+
+// Leader forwarding modes
+const (
+	ForwardProxy    = "proxy"
+	ForwardRedirect = "redirect"
+)
+
+// forwardToLeader wraps a handler so that writes and non-stale reads arriving
+// at a follower are proxied (or redirected) to the leader instead of failing
+func (s *Server) forwardToLeader(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.needsLeader(r) || s.store.IsLeader() {
+			next(w, r)
+			return
+		}
+
+		if by := r.Header.Get(forwardedHeader); by != "" {
+			writeError(w, "Request forwarded by "+by+" but this node is not the leader", http.StatusServiceUnavailable)
+			return
+		}
+
+		leaderAddr, ok := s.store.LeaderHTTPAddr()
+		if !ok {
+			writeError(w, "No known leader", http.StatusServiceUnavailable)
+			return
+		}
+
+		leaderURL := &url.URL{Scheme: "http", Host: leaderAddr}
+
+		if s.forwardMode == ForwardRedirect {
+			http.Redirect(w, r, leaderURL.String()+r.URL.RequestURI(), http.StatusTemporaryRedirect)
+			return
+		}
+
+		proxy := httputil.NewSingleHostReverseProxy(leaderURL)
+		proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+			log.Printf("Failed to forward %s %s to leader at %s: %v", r.Method, r.URL.Path, leaderAddr, err)
+			writeError(w, "Failed to reach leader", http.StatusBadGateway)
+		}
+		r.Header.Set(forwardedHeader, s.store.ID())
+		proxy.ServeHTTP(w, r)
+	}
+}
@@ -0,0 +1,119 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"raft3d/fsm"
+)
+
+// JobRequest represents the input to create a print job. Leaving out the
+// printer hands the job to the scheduler, which picks an idle printer that
+// satisfies the constraints.
+type JobRequest struct {
+	PrinterID      string              `json:"printer_id,omitempty"`
+	FilamentID     string              `json:"filament_id,omitempty"`
+	FilamentWeight float64             `json:"filament_weight"`
+	Constraints    *fsm.JobConstraints `json:"constraints,omitempty"`
+}
+
+func (s *Server) submitJobHandler(w http.ResponseWriter, r *http.Request) {
+	var jobReq JobRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&jobReq); err != nil {
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// The FSM validates the printer and filament and assigns the job to the
+	// printer atomically, so there is nothing to check here
+	job := fsm.PrintJob{
+		PrinterID:      jobReq.PrinterID,
+		FilamentID:     jobReq.FilamentID,
+		FilamentWeight: jobReq.FilamentWeight,
+		Constraints:    jobReq.Constraints,
+	}
+
+	// Create command
+	command := fsm.Command{
+		Type: fsm.CommandSubmitJob,
+		Job:  &job,
+	}
+
+	response, ok := s.applyCommand(w, r, command)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s *Server) getJobsHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.prepareRead(w, r) {
+		return
+	}
+
+	// Return all jobs
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.store.FSM().Jobs())
+}
+
+func (s *Server) getJobHandler(w http.ResponseWriter, r *http.Request) {
+	// Extract job ID from URL path
+	jobID := r.URL.Path[len("/jobs/"):]
+
+	if !s.waitForChange(w, r, fsm.KindJob, jobID) {
+		return
+	}
+
+	if !s.prepareRead(w, r) {
+		return
+	}
+
+	job, exists := s.store.FSM().Job(jobID)
+	if !exists {
+		writeError(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	setETag(w, job.ModifyIndex)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+func (s *Server) updateJobStatusHandler(w http.ResponseWriter, r *http.Request) {
+	// Extract job ID from URL path
+	jobID := r.URL.Path[len("/jobs/"):]
+
+	var statusUpdate struct {
+		Status   string  `json:"status"`
+		CASIndex *uint64 `json:"cas_index,omitempty"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&statusUpdate); err != nil {
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	index, ok := casIndex(w, r, statusUpdate.CASIndex)
+	if !ok {
+		return
+	}
+
+	// Create a command to update job status
+	command := fsm.Command{
+		Type:     fsm.CommandUpdateJobStatus,
+		JobID:    jobID,
+		Status:   statusUpdate.Status,
+		CASIndex: index,
+	}
+
+	job, ok := s.applyCommand(w, r, command)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
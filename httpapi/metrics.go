@@ -0,0 +1,135 @@
+package httpapi
+
+import (
+	"log"
+	"net/http"
+	"sort"
+
+	metrics "github.com/armon/go-metrics"
+
+	"raft3d/fsm"
+	"raft3d/telemetry"
+)
+
+// /metrics handler. Serves this node's metrics in the Prometheus text format:
+// what Raft and the node emitted through go-metrics, plus Raft indexes and
+// the domain state read from the local FSM at scrape time.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var families []telemetry.Family
+	if s.metrics != nil {
+		families = s.metrics.Families()
+	}
+	families = append(families, s.raftFamilies()...)
+	families = append(families, s.domainFamilies()...)
+
+	w.Header().Set("Content-Type", telemetry.ContentType)
+	if err := telemetry.Write(w, families); err != nil {
+		log.Printf("Failed to write metrics: %v", err)
+	}
+}
+
+// raftFamilies reports the node's Raft state and log indexes
+func (s *Server) raftFamilies() []telemetry.Family {
+	status := s.store.Status()
+
+	var lastSnapshot uint64
+	if status.LastSnapshot != nil {
+		lastSnapshot = status.LastSnapshot.Index
+	}
+
+	var leader float64
+	if s.store.IsLeader() {
+		leader = 1
+	}
+
+	return []telemetry.Family{
+		gauge("raft3d_raft_leader", "Whether this node is the leader", leader),
+		gauge("raft3d_raft_term", "Current Raft term", float64(status.Term)),
+		gauge("raft3d_raft_commit_index", "Index of the last committed log entry", float64(status.CommitIndex)),
+		gauge("raft3d_raft_applied_index", "Index of the last log entry applied to the FSM", float64(status.AppliedIndex)),
+		gauge("raft3d_raft_last_log_index", "Index of the last entry in the local log", float64(status.LastLogIndex)),
+		gauge("raft3d_raft_last_snapshot_index", "Index of the newest snapshot on disk", float64(lastSnapshot)),
+	}
+}
+
+// domainFamilies reports printers, jobs and filament from the local FSM
+func (s *Server) domainFamilies() []telemetry.Family {
+	state := s.store.FSM()
+
+	printers := state.Printers()
+	filaments := state.Filaments()
+
+	printerStatuses := make(map[string]int)
+	var printerIDs []string
+	for id, printer := range printers {
+		printerStatuses[printer.Status]++
+		printerIDs = append(printerIDs, id)
+	}
+	sort.Strings(printerIDs)
+
+	// Every printer is reported, with 0 remaining when no spool is loaded
+	remaining := telemetry.Family{
+		Name: "raft3d_printer_filament_remaining",
+		Help: "Remaining weight of the filament loaded in each printer",
+		Type: telemetry.TypeGauge,
+	}
+	for _, id := range printerIDs {
+		var weight float64
+		if filament, exists := filaments[printers[id].FilamentID]; exists {
+			weight = filament.RemainingWeight
+		}
+		remaining.Samples = append(remaining.Samples, telemetry.Sample{Labels: label("printer", id), Value: weight})
+	}
+
+	jobStatuses := make(map[string]int)
+	for _, job := range state.Jobs() {
+		jobStatuses[job.Status]++
+	}
+
+	jobs := telemetry.Family{Name: "raft3d_jobs", Help: "Print jobs by status", Type: telemetry.TypeGauge}
+	for _, status := range fsm.JobStatuses() {
+		jobs.Samples = append(jobs.Samples, telemetry.Sample{Labels: label("status", status), Value: float64(jobStatuses[status])})
+	}
+
+	counts := state.Counts()
+	return []telemetry.Family{
+		countsByLabel("raft3d_printers", "Printers by status", "status", printerStatuses),
+		jobs,
+		remaining,
+		gauge("raft3d_unassigned_jobs", "Jobs waiting in the unassigned pool", float64(counts.Unassigned)),
+		gauge("raft3d_filaments", "Filament spools", float64(counts.Filaments)),
+	}
+}
+
+func gauge(name, help string, value float64) telemetry.Family {
+	return telemetry.Family{
+		Name:    name,
+		Help:    help,
+		Type:    telemetry.TypeGauge,
+		Samples: []telemetry.Sample{{Value: value}},
+	}
+}
+
+// countsByLabel is a gauge family with one sample per key of counts
+func countsByLabel(name, help, labelName string, counts map[string]int) telemetry.Family {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	family := telemetry.Family{Name: name, Help: help, Type: telemetry.TypeGauge}
+	for _, key := range keys {
+		family.Samples = append(family.Samples, telemetry.Sample{Labels: label(labelName, key), Value: float64(counts[key])})
+	}
+	return family
+}
+
+func label(name, value string) []metrics.Label {
+	return []metrics.Label{{Name: name, Value: value}}
+}
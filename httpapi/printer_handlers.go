@@ -0,0 +1,79 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"raft3d/fsm"
+)
+
+// PrinterRequest represents the input to create a printer
+type PrinterRequest struct {
+	Name        string           `json:"name"`
+	BuildVolume *fsm.BuildVolume `json:"build_volume,omitempty"`
+	Tags        []string         `json:"tags,omitempty"`
+}
+
+func (s *Server) createPrinterHandler(w http.ResponseWriter, r *http.Request) {
+	var printerReq PrinterRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&printerReq); err != nil {
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// The FSM assigns the ID when the command is applied
+	printer := fsm.Printer{
+		Name:        printerReq.Name,
+		Status:      "idle",
+		BuildVolume: printerReq.BuildVolume,
+		Tags:        printerReq.Tags,
+	}
+
+	// Create command
+	command := fsm.Command{
+		Type:    fsm.CommandCreatePrinter,
+		Printer: &printer,
+	}
+
+	response, ok := s.applyCommand(w, r, command)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s *Server) getPrintersHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.prepareRead(w, r) {
+		return
+	}
+
+	// Return all printers
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.store.FSM().Printers())
+}
+
+func (s *Server) getPrinterHandler(w http.ResponseWriter, r *http.Request) {
+	// Extract printer ID from URL path
+	printerID := r.URL.Path[len("/printers/"):]
+
+	if !s.waitForChange(w, r, fsm.KindPrinter, printerID) {
+		return
+	}
+
+	if !s.prepareRead(w, r) {
+		return
+	}
+
+	printer, exists := s.store.FSM().Printer(printerID)
+	if !exists {
+		writeError(w, "Printer not found", http.StatusNotFound)
+		return
+	}
+
+	setETag(w, printer.ModifyIndex)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(printer)
+}
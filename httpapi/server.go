@@ -0,0 +1,179 @@
+// Package httpapi serves the REST API for printers, filaments and print jobs
+// on top of a store.Store, forwarding requests that need the leader.
+package httpapi
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"raft3d/store"
+	"raft3d/telemetry"
+)
+
+// Config controls how a node serves requests
+type Config struct {
+	// ForwardMode is how followers handle requests that need the leader:
+	// ForwardProxy (the default) or ForwardRedirect
+	ForwardMode string
+	// ReadConsistency is the mode used by reads that don't ask for one;
+	// ConsistencyLeader if empty
+	ReadConsistency string
+	// Metrics is the sink installed with telemetry.Setup, served on /metrics
+	// with the node's own gauges. Without one only those gauges are served.
+	Metrics *telemetry.Sink
+}
+
+// Server is the HTTP API of one node
+type Server struct {
+	store              *store.Store
+	forwardMode        string
+	defaultConsistency string
+	metrics            *telemetry.Sink
+
+	// closeStreams is closed by CloseStreams to end event streams and
+	// blocking reads
+	closeStreams chan struct{}
+	closeOnce    sync.Once
+}
+
+// New returns a Server for st
+func New(st *store.Store, cfg Config) (*Server, error) {
+	if cfg.ForwardMode == "" {
+		cfg.ForwardMode = ForwardProxy
+	}
+	if cfg.ForwardMode != ForwardProxy && cfg.ForwardMode != ForwardRedirect {
+		return nil, fmt.Errorf("invalid forward mode %q", cfg.ForwardMode)
+	}
+
+	if cfg.ReadConsistency == "" {
+		cfg.ReadConsistency = ConsistencyLeader
+	}
+	if !ValidConsistency(cfg.ReadConsistency) {
+		return nil, fmt.Errorf("invalid read consistency mode %q", cfg.ReadConsistency)
+	}
+
+	return &Server{
+		store:              st,
+		forwardMode:        cfg.ForwardMode,
+		defaultConsistency: cfg.ReadConsistency,
+		metrics:            cfg.Metrics,
+		closeStreams:       make(chan struct{}),
+	}, nil
+}
+
+// Handler returns the routes of the API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/join", s.forwardToLeader(s.handleJoin))
+	mux.HandleFunc("/status", s.handleStatus)
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	mux.HandleFunc("/events", s.handleEvents)
+
+	// Cluster membership handlers
+	mux.HandleFunc("/cluster/members", s.forwardToLeader(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			s.getMembersHandler(w, r)
+		} else if r.Method == http.MethodPost {
+			s.addMemberHandler(w, r)
+		} else {
+			writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	mux.HandleFunc("/cluster/leadership/transfer", s.forwardToLeader(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			s.transferLeadershipHandler(w, r)
+		} else {
+			writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	mux.HandleFunc("/cluster/members/", s.forwardToLeader(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			s.updateMemberHandler(w, r)
+		} else if r.Method == http.MethodDelete {
+			s.removeMemberHandler(w, r)
+		} else {
+			writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Job handlers
+	mux.HandleFunc("/jobs", s.forwardToLeader(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			s.submitJobHandler(w, r)
+		} else if r.Method == http.MethodGet {
+			s.getJobsHandler(w, r)
+		} else {
+			writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	mux.HandleFunc("/jobs/", s.forwardToLeader(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			s.getJobHandler(w, r)
+		} else if r.Method == http.MethodPut {
+			s.updateJobStatusHandler(w, r)
+		} else {
+			writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Printer handlers
+	mux.HandleFunc("/printers", s.forwardToLeader(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			s.createPrinterHandler(w, r)
+		} else if r.Method == http.MethodGet {
+			s.getPrintersHandler(w, r)
+		} else {
+			writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	mux.HandleFunc("/printers/", s.forwardToLeader(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/filament") {
+			if r.Method == http.MethodPut {
+				s.loadFilamentHandler(w, r)
+			} else if r.Method == http.MethodDelete {
+				s.unloadFilamentHandler(w, r)
+			} else {
+				writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		if r.Method == http.MethodGet {
+			s.getPrinterHandler(w, r)
+		} else {
+			writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// Filament handlers
+	mux.HandleFunc("/filaments", s.forwardToLeader(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			s.createFilamentHandler(w, r)
+		} else if r.Method == http.MethodGet {
+			s.getFilamentsHandler(w, r)
+		} else {
+			writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	mux.HandleFunc("/filaments/", s.forwardToLeader(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			s.getFilamentHandler(w, r)
+		} else if r.Method == http.MethodPut {
+			s.updateFilamentHandler(w, r)
+		} else if r.Method == http.MethodDelete {
+			s.deleteFilamentHandler(w, r)
+		} else {
+			writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	return mux
+}
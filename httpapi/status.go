@@ -0,0 +1,169 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"raft3d/store"
+)
+
+// peerStatusTimeout bounds how long /status waits for each peer's own status
+const peerStatusTimeout = 2 * time.Second
+
+// StatusResponse is the body of GET /status
+type StatusResponse struct {
+	store.Status
+	Peers []PeerStatus `json:"peers,omitempty"`
+}
+
+// PeerStatus is one member of the cluster as seen from this node. Raft doesn't
+// expose how far each follower has replicated, so the applied index is asked
+// of the peer itself over HTTP and Lag is measured against this node's commit
+// index.
+type PeerStatus struct {
+	store.Member
+	AppliedIndex *uint64 `json:"applied_index,omitempty"`
+	Lag          *uint64 `json:"lag,omitempty"`
+	Error        string  `json:"error,omitempty"`
+}
+
+// /status handler. Peers are left out with ?peers=false, which is how nodes
+// ask each other, and ?format=text gives a summary for humans.
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	status := StatusResponse{Status: s.store.Status()}
+
+	if r.URL.Query().Get("peers") != "false" {
+		peers, err := s.peerStatuses(status.CommitIndex)
+		if err != nil {
+			writeError(w, err.Error(), clusterErrorStatus(err))
+			return
+		}
+		status.Peers = peers
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status)
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		writeStatusText(w, status)
+	default:
+		writeError(w, "Format must be \"json\" or \"text\"", http.StatusBadRequest)
+	}
+}
+
+// peerStatuses fetches the status of every other member in parallel
+func (s *Server) peerStatuses(commitIndex uint64) ([]PeerStatus, error) {
+	members, err := s.store.Members()
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: peerStatusTimeout}
+	peers := make([]PeerStatus, len(members))
+
+	var wg sync.WaitGroup
+	for i, member := range members {
+		peers[i].Member = member
+		if member.ID == s.store.ID() {
+			applied := s.store.Raft().AppliedIndex()
+			peers[i].AppliedIndex = &applied
+			peers[i].Lag = lag(commitIndex, applied)
+			continue
+		}
+		if member.HTTPAddr == "" {
+			peers[i].Error = "HTTP address not known"
+			continue
+		}
+
+		wg.Add(1)
+		go func(peer *PeerStatus) {
+			defer wg.Done()
+
+			applied, err := fetchAppliedIndex(client, peer.HTTPAddr)
+			if err != nil {
+				peer.Error = err.Error()
+				return
+			}
+			peer.AppliedIndex = &applied
+			peer.Lag = lag(commitIndex, applied)
+		}(&peers[i])
+	}
+	wg.Wait()
+
+	return peers, nil
+}
+
+// fetchAppliedIndex asks the node at addr how far it has applied the log
+func fetchAppliedIndex(client *http.Client, addr string) (uint64, error) {
+	resp, err := client.Get(fmt.Sprintf("http://%s/status?peers=false", addr))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("status request failed: %s", resp.Status)
+	}
+
+	var status store.Status
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return 0, fmt.Errorf("invalid status response: %w", err)
+	}
+	return status.AppliedIndex, nil
+}
+
+// lag is how many committed entries a node has yet to apply
+func lag(commitIndex, appliedIndex uint64) *uint64 {
+	var behind uint64
+	if commitIndex > appliedIndex {
+		behind = commitIndex - appliedIndex
+	}
+	return &behind
+}
+
+func writeStatusText(w http.ResponseWriter, status StatusResponse) {
+	fmt.Fprintf(w, "State: %s\n", status.State)
+	fmt.Fprintf(w, "Leader: %s (%s)\n", status.LeaderAddress, status.LeaderID)
+	fmt.Fprintf(w, "Node: %s\n", status.ID)
+	fmt.Fprintf(w, "Term: %d\n", status.Term)
+	fmt.Fprintf(w, "Index: commit %d, applied %d, last log %d\n", status.CommitIndex, status.AppliedIndex, status.LastLogIndex)
+
+	if snapshot := status.LastSnapshot; snapshot != nil {
+		fmt.Fprintf(w, "Last snapshot: index %d at %s\n", snapshot.Index, snapshot.Time.Format(time.RFC3339))
+	} else {
+		fmt.Fprintf(w, "Last snapshot: none\n")
+	}
+
+	counts := status.Entities
+	fmt.Fprintf(w, "Entities: %d jobs (%d unassigned), %d printers, %d filaments, %d nodes\n",
+		counts.Jobs, counts.Unassigned, counts.Printers, counts.Filaments, counts.Nodes)
+
+	if len(status.Peers) > 0 {
+		fmt.Fprintf(w, "Peers:\n")
+		for _, peer := range status.Peers {
+			replication := peer.Error
+			if peer.Lag != nil {
+				replication = fmt.Sprintf("applied %d, lag %d", *peer.AppliedIndex, *peer.Lag)
+			}
+			fmt.Fprintf(w, "  %s %s %s: %s\n", peer.ID, peer.Address, peer.Suffrage, replication)
+		}
+	}
+
+	keys := make([]string, 0, len(status.Stats))
+	for key := range status.Stats {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintf(w, "Raft stats:\n")
+	for _, key := range keys {
+		fmt.Fprintf(w, "  %s: %s\n", key, strings.TrimSpace(status.Stats[key]))
+	}
+}
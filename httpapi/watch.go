@@ -0,0 +1,66 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Blocking read limits for ?wait=
+const (
+	defaultWaitTimeout = 30 * time.Second
+	maxWaitTimeout     = 10 * time.Minute
+)
+
+// waitForChange makes a read of one entity a blocking query when it has
+// ?wait=<index>: it holds the request until the entity changes after that
+// Raft index, or until ?timeout= (default 30s) passes, and then sets
+// X-Raft-Index for the response. The client passes that header back as the
+// next wait index. The wait happens on whichever node serves the read, woken
+// by its FSM applying the log. Spurious returns are possible, as with
+// Consul's blocking queries. Handlers call prepareRead after it, so the
+// consistency mode is enforced when the entity is read rather than before the
+// wait. It writes an error response and returns false if the parameters are
+// invalid.
+func (s *Server) waitForChange(w http.ResponseWriter, r *http.Request, kind, id string) bool {
+	query := r.URL.Query()
+
+	// An invalid mode is rejected by prepareRead without waiting first
+	if query.Has("wait") && ValidConsistency(s.readConsistency(r)) {
+		index, err := strconv.ParseUint(query.Get("wait"), 10, 64)
+		if err != nil {
+			writeError(w, "Invalid wait index", http.StatusBadRequest)
+			return false
+		}
+
+		timeout := defaultWaitTimeout
+		if query.Has("timeout") {
+			timeout, err = time.ParseDuration(query.Get("timeout"))
+			if err != nil || timeout <= 0 {
+				writeError(w, "Invalid timeout", http.StatusBadRequest)
+				return false
+			}
+			timeout = min(timeout, maxWaitTimeout)
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		// Release waiting clients when the server shuts down
+		go func() {
+			select {
+			case <-s.closeStreams:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		s.store.WaitForChange(ctx, kind, id, index)
+	}
+
+	// Taken before the entity is read, so a change racing with the read is
+	// reported again rather than missed
+	w.Header().Set("X-Raft-Index", strconv.FormatUint(s.store.LastEventIndex(), 10))
+	return true
+}
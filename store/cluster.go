@@ -0,0 +1,146 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hashicorp/raft"
+
+	"raft3d/fsm"
+)
+
+// ErrAddressInUse is returned by Join when another server already has the
+// joining node's Raft address
+var ErrAddressInUse = errors.New("raft address in use by another server")
+
+// Join adds a node to the cluster, as a voter or as a non-voter that only
+// replicates the log, and records its HTTP address so it can be found if it
+// becomes leader. It must be called on the leader. A node that is already a
+// member with the same address is left as is, so restarted nodes can join
+// again; use SetVoter to change its suffrage.
+func (s *Store) Join(id, raftAddr, httpAddr string, voter bool) error {
+	configFuture := s.raft.GetConfiguration()
+	if err := configFuture.Error(); err != nil {
+		return err
+	}
+
+	member := false
+	for _, server := range configFuture.Configuration().Servers {
+		if server.Address != raft.ServerAddress(raftAddr) {
+			continue
+		}
+		if server.ID != raft.ServerID(id) {
+			return fmt.Errorf("%w: %s is used by %s", ErrAddressInUse, raftAddr, server.ID)
+		}
+		member = true
+	}
+
+	if !member {
+		// An existing server with the same ID but a new address is updated
+		var f raft.IndexFuture
+		if voter {
+			f = s.raft.AddVoter(raft.ServerID(id), raft.ServerAddress(raftAddr), 0, 0)
+		} else {
+			f = s.raft.AddNonvoter(raft.ServerID(id), raft.ServerAddress(raftAddr), 0, 0)
+		}
+		if err := f.Error(); err != nil {
+			return err
+		}
+	}
+
+	if httpAddr != "" {
+		if err := s.RegisterNode(id, httpAddr); err != nil {
+			log.Printf("Failed to register node metadata for %s: %v", id, err)
+		}
+	}
+
+	return nil
+}
+
+// RegisterNode replicates the HTTP address of a node, skipping the write if
+// the FSM already has it
+func (s *Store) RegisterNode(id, httpAddr string) error {
+	if node, exists := s.fsm.Node(id); exists && node.HTTPAddr == httpAddr {
+		return nil
+	}
+
+	_, err := s.Apply(fsm.Command{
+		Type: fsm.CommandRegisterNode,
+		Node: &fsm.NodeMeta{ID: id, HTTPAddr: httpAddr},
+	})
+	return err
+}
+
+// registerSelfOnLeadership publishes this node's HTTP address each time it
+// becomes leader, so followers can always resolve where to forward writes,
+// until Shutdown
+func (s *Store) registerSelfOnLeadership(httpAddr string) {
+	leaderCh := s.raft.LeaderCh()
+	for {
+		select {
+		case isLeader := <-leaderCh:
+			if !isLeader {
+				continue
+			}
+			if err := s.RegisterNode(s.id, httpAddr); err != nil {
+				log.Printf("Failed to register node metadata: %v", err)
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// LeaderHTTPAddr resolves the current leader's HTTP address from the
+// replicated node metadata
+func (s *Store) LeaderHTTPAddr() (string, bool) {
+	_, leaderID := s.raft.LeaderWithID()
+	if leaderID == "" {
+		return "", false
+	}
+
+	node, exists := s.fsm.Node(string(leaderID))
+	if !exists || node.HTTPAddr == "" {
+		return "", false
+	}
+
+	return node.HTTPAddr, true
+}
+
+// ParsePeers parses a comma-separated list of id=addr pairs, as taken by
+// -peers, into the servers of an initial Raft configuration
+func ParsePeers(list string) ([]raft.Server, error) {
+	var servers []raft.Server
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		id, addr, ok := strings.Cut(entry, "=")
+		if !ok || id == "" || addr == "" {
+			return nil, fmt.Errorf("invalid peer %q, expected id=addr", entry)
+		}
+		if hasServer(servers, id) {
+			return nil, fmt.Errorf("duplicate peer ID %q", id)
+		}
+
+		servers = append(servers, raft.Server{
+			Suffrage: raft.Voter,
+			ID:       raft.ServerID(id),
+			Address:  raft.ServerAddress(addr),
+		})
+	}
+	return servers, nil
+}
+
+func hasServer(servers []raft.Server, id string) bool {
+	for _, server := range servers {
+		if server.ID == raft.ServerID(id) {
+			return true
+		}
+	}
+	return false
+}
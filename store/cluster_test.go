@@ -0,0 +1,57 @@
+package store
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/raft"
+)
+
+func TestParsePeers(t *testing.T) {
+	voter := func(id, addr string) raft.Server {
+		return raft.Server{Suffrage: raft.Voter, ID: raft.ServerID(id), Address: raft.ServerAddress(addr)}
+	}
+
+	tests := []struct {
+		name    string
+		list    string
+		want    []raft.Server
+		wantErr string
+	}{
+		{name: "empty", list: ""},
+		{name: "only separators", list: " , ,"},
+		{
+			name: "three peers",
+			list: "n1=127.0.0.1:9001,n2=127.0.0.1:9002,n3=127.0.0.1:9003",
+			want: []raft.Server{voter("n1", "127.0.0.1:9001"), voter("n2", "127.0.0.1:9002"), voter("n3", "127.0.0.1:9003")},
+		},
+		{
+			name: "blank entries and spaces",
+			list: " n1=127.0.0.1:9001 ,, n2=127.0.0.1:9002,",
+			want: []raft.Server{voter("n1", "127.0.0.1:9001"), voter("n2", "127.0.0.1:9002")},
+		},
+		{name: "missing =", list: "n1=127.0.0.1:9001,n2", wantErr: `invalid peer "n2"`},
+		{name: "missing ID", list: "=127.0.0.1:9001", wantErr: "invalid peer"},
+		{name: "missing address", list: "n1=", wantErr: "invalid peer"},
+		{name: "duplicate ID", list: "n1=127.0.0.1:9001,n1=127.0.0.1:9002", wantErr: `duplicate peer ID "n1"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePeers(tt.list)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParsePeers: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
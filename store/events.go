@@ -0,0 +1,144 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"raft3d/fsm"
+)
+
+// eventBufferSize is how many recent events each node keeps for clients
+// resuming a stream
+const eventBufferSize = 4096
+
+// ErrEventsTruncated is returned when a client resumes from an index whose
+// following events are no longer buffered, after they aged out or the state
+// was restored from a snapshot. The client must reload the state instead.
+var ErrEventsTruncated = errors.New("events after the requested index are no longer available")
+
+// eventLog buffers the events of recently applied commands. Every node
+// applies the same log, so a client can resume from an event's index on any
+// node.
+type eventLog struct {
+	mu     sync.Mutex
+	events []fsm.Event // oldest first
+	// floor is the highest index not covered by events: every event after it
+	// is buffered
+	floor uint64
+	// last is the index of the newest event or restored snapshot
+	last uint64
+	// wake is closed and replaced whenever an event is published
+	wake chan struct{}
+}
+
+func newEventLog() *eventLog {
+	return &eventLog{wake: make(chan struct{})}
+}
+
+// publish adds an event, or on a reset drops everything buffered before the
+// restored snapshot's index
+func (l *eventLog) publish(event fsm.Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if event.Reset {
+		l.events = nil
+		l.floor = event.Index
+	} else {
+		l.events = append(l.events, event)
+		// Trim in batches so publishing stays cheap
+		if len(l.events) > 2*eventBufferSize {
+			dropped := len(l.events) - eventBufferSize
+			l.floor = l.events[dropped-1].Index
+			l.events = append([]fsm.Event(nil), l.events[dropped:]...)
+		}
+	}
+	l.last = event.Index
+
+	close(l.wake)
+	l.wake = make(chan struct{})
+}
+
+// since returns the buffered events after index, and a channel that is closed
+// when the next event is published
+func (l *eventLog) since(index uint64) ([]fsm.Event, <-chan struct{}, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if index < l.floor {
+		return nil, l.wake, ErrEventsTruncated
+	}
+
+	// Events are in index order; skip the ones the client has seen
+	start := len(l.events)
+	for start > 0 && l.events[start-1].Index > index {
+		start--
+	}
+	return append([]fsm.Event(nil), l.events[start:]...), l.wake, nil
+}
+
+func (l *eventLog) lastIndex() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.last
+}
+
+// Events returns the events applied after index, oldest first, and a channel
+// that is closed when another is applied. It returns ErrEventsTruncated if
+// some of those events are no longer buffered.
+func (s *Store) Events(index uint64) ([]fsm.Event, <-chan struct{}, error) {
+	return s.events.since(index)
+}
+
+// LastEventIndex returns the log index of the newest event, where a client
+// with no index to resume from starts streaming
+func (s *Store) LastEventIndex() uint64 {
+	return s.events.lastIndex()
+}
+
+// WaitForChange blocks until an event after index changes the entity of the
+// given kind and ID, or ctx is done. It also returns when it can't tell, because
+// the events after index are no longer buffered, so callers must treat a
+// return as a possible change. Every node applies the same log, so index may
+// come from any node.
+func (s *Store) WaitForChange(ctx context.Context, kind, id string, index uint64) {
+	for {
+		events, wake, err := s.events.since(index)
+		if err != nil {
+			return
+		}
+
+		for _, event := range events {
+			for _, change := range event.Changes {
+				if change.Kind == kind && change.ID == id {
+					return
+				}
+			}
+			index = event.Index
+		}
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// publishEvent is the FSM's notify hook. It wakes the scheduler and buffers
+// the event for streaming; a restore is recorded at the index of the
+// snapshot it came from.
+func (s *Store) publishEvent(event fsm.Event) {
+	if event.Reset {
+		// Raft restores from the newest snapshot in the store, both at startup
+		// and after installing one sent by the leader
+		if snapshots, err := s.snapshots.List(); err == nil && len(snapshots) > 0 {
+			event.Index = snapshots[0].Index
+		}
+	}
+
+	s.events.publish(event)
+	s.scheduler.Notify()
+}
@@ -0,0 +1,217 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"raft3d/fsm"
+)
+
+func publishRange(l *eventLog, from, to uint64) {
+	for index := from; index <= to; index++ {
+		l.publish(fsm.Event{Index: index})
+	}
+}
+
+func eventIndexes(events []fsm.Event) []uint64 {
+	var indexes []uint64
+	for _, event := range events {
+		indexes = append(indexes, event.Index)
+	}
+	return indexes
+}
+
+func TestEventLogSince(t *testing.T) {
+	tests := []struct {
+		name      string
+		setup     func(l *eventLog)
+		since     uint64
+		wantFirst uint64 // first event returned, 0 for none
+		wantCount int
+		wantErr   error
+		wantLast  uint64
+	}{
+		{
+			name:      "from the start",
+			setup:     func(l *eventLog) { publishRange(l, 1, 5) },
+			since:     0,
+			wantFirst: 1, wantCount: 5, wantLast: 5,
+		},
+		{
+			name:      "after an index",
+			setup:     func(l *eventLog) { publishRange(l, 1, 5) },
+			since:     3,
+			wantFirst: 4, wantCount: 2, wantLast: 5,
+		},
+		{
+			name:     "caught up",
+			setup:    func(l *eventLog) { publishRange(l, 1, 5) },
+			since:    5,
+			wantLast: 5,
+		},
+		{
+			// The buffer is trimmed back to eventBufferSize once it holds
+			// twice that
+			name:     "aged out",
+			setup:    func(l *eventLog) { publishRange(l, 1, 2*eventBufferSize+1) },
+			since:    eventBufferSize,
+			wantErr:  ErrEventsTruncated,
+			wantLast: 2*eventBufferSize + 1,
+		},
+		{
+			name:      "oldest still buffered",
+			setup:     func(l *eventLog) { publishRange(l, 1, 2*eventBufferSize+1) },
+			since:     eventBufferSize + 1,
+			wantFirst: eventBufferSize + 2, wantCount: eventBufferSize, wantLast: 2*eventBufferSize + 1,
+		},
+		{
+			name: "before a restore",
+			setup: func(l *eventLog) {
+				publishRange(l, 1, 3)
+				l.publish(fsm.Event{Index: 10, Reset: true})
+			},
+			since:    3,
+			wantErr:  ErrEventsTruncated,
+			wantLast: 10,
+		},
+		{
+			name: "after a restore",
+			setup: func(l *eventLog) {
+				publishRange(l, 1, 3)
+				l.publish(fsm.Event{Index: 10, Reset: true})
+				publishRange(l, 11, 12)
+			},
+			since:     10,
+			wantFirst: 11, wantCount: 2, wantLast: 12,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newEventLog()
+			tt.setup(l)
+
+			events, _, err := l.since(tt.since)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if len(events) != tt.wantCount {
+				t.Fatalf("got %d events, want %d", len(events), tt.wantCount)
+			}
+			for i, index := range eventIndexes(events) {
+				if index != tt.wantFirst+uint64(i) {
+					t.Fatalf("event %d has index %d, want %d", i, index, tt.wantFirst+uint64(i))
+				}
+			}
+			if last := l.lastIndex(); last != tt.wantLast {
+				t.Errorf("last index %d, want %d", last, tt.wantLast)
+			}
+		})
+	}
+}
+
+func TestEventLogWake(t *testing.T) {
+	l := newEventLog()
+	_, wake, _ := l.since(0)
+
+	select {
+	case <-wake:
+		t.Fatal("woken before any event")
+	default:
+	}
+
+	l.publish(fsm.Event{Index: 1})
+	select {
+	case <-wake:
+	default:
+		t.Fatal("not woken by an event")
+	}
+
+	_, next, _ := l.since(1)
+	if next == wake {
+		t.Fatal("wake channel not replaced after publishing")
+	}
+}
+
+func printerChanged(index uint64, id string) fsm.Event {
+	return fsm.Event{Index: index, Changes: []fsm.Change{{Kind: fsm.KindPrinter, ID: id}}}
+}
+
+// waitForChange runs WaitForChange in the background and returns a channel
+// closed when it returns
+func waitForChange(ctx context.Context, s *Store, id string, index uint64) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		s.WaitForChange(ctx, fsm.KindPrinter, id, index)
+		close(done)
+	}()
+	return done
+}
+
+func TestWaitForChange(t *testing.T) {
+	const blocked = 50 * time.Millisecond
+
+	returned := func(t *testing.T, done <-chan struct{}) {
+		t.Helper()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("WaitForChange did not return")
+		}
+	}
+	stillBlocked := func(t *testing.T, done <-chan struct{}) {
+		t.Helper()
+		select {
+		case <-done:
+			t.Fatal("WaitForChange returned early")
+		case <-time.After(blocked):
+		}
+	}
+
+	t.Run("change already buffered", func(t *testing.T) {
+		s := &Store{events: newEventLog()}
+		s.events.publish(printerChanged(1, "printer-1"))
+
+		returned(t, waitForChange(context.Background(), s, "printer-1", 0))
+	})
+
+	t.Run("woken by a change to the entity", func(t *testing.T) {
+		s := &Store{events: newEventLog()}
+		s.events.publish(printerChanged(1, "printer-1"))
+
+		done := waitForChange(context.Background(), s, "printer-1", 1)
+		stillBlocked(t, done)
+
+		// Changes to other entities don't end the wait
+		s.events.publish(printerChanged(2, "printer-2"))
+		s.events.publish(fsm.Event{Index: 3, Changes: []fsm.Change{{Kind: fsm.KindJob, ID: "printer-1"}}})
+		stillBlocked(t, done)
+
+		s.events.publish(printerChanged(4, "printer-1"))
+		returned(t, done)
+	})
+
+	t.Run("woken by a restore", func(t *testing.T) {
+		s := &Store{events: newEventLog()}
+		s.events.publish(printerChanged(1, "printer-1"))
+
+		done := waitForChange(context.Background(), s, "printer-1", 1)
+		stillBlocked(t, done)
+
+		s.events.publish(fsm.Event{Index: 10, Reset: true})
+		returned(t, done)
+	})
+
+	t.Run("context done", func(t *testing.T) {
+		s := &Store{events: newEventLog()}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := waitForChange(ctx, s, "printer-1", 0)
+		stillBlocked(t, done)
+
+		cancel()
+		returned(t, done)
+	})
+}
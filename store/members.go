@@ -0,0 +1,174 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/raft"
+)
+
+// ErrUnknownMember is returned for a server ID that isn't in the Raft configuration
+var ErrUnknownMember = errors.New("unknown cluster member")
+
+// Member is a server in the Raft configuration
+type Member struct {
+	ID       string `json:"id"`
+	Address  string `json:"address"`
+	Suffrage string `json:"suffrage"` // "voter", "nonvoter" or "staging"
+	Leader   bool   `json:"leader"`
+	HTTPAddr string `json:"http_addr,omitempty"`
+
+	// Healthy and LastContactMs are only known on the leader, which tracks
+	// heartbeats, and for the leader on a follower. LastContactMs is set for
+	// members that are not being heard from.
+	Healthy       *bool  `json:"healthy,omitempty"`
+	LastContactMs *int64 `json:"last_contact_ms,omitempty"`
+}
+
+// Members lists the current Raft configuration
+func (s *Store) Members() ([]Member, error) {
+	configFuture := s.raft.GetConfiguration()
+	if err := configFuture.Error(); err != nil {
+		return nil, err
+	}
+
+	_, leaderID := s.raft.LeaderWithID()
+	isLeader := s.IsLeader()
+
+	var members []Member
+	for _, server := range configFuture.Configuration().Servers {
+		member := Member{
+			ID:       string(server.ID),
+			Address:  string(server.Address),
+			Suffrage: strings.ToLower(server.Suffrage.String()),
+			Leader:   server.ID == leaderID,
+		}
+		if node, exists := s.fsm.Node(member.ID); exists {
+			member.HTTPAddr = node.HTTPAddr
+		}
+
+		switch {
+		case isLeader && server.ID == leaderID:
+			member.Healthy = boolPtr(true)
+		case isLeader:
+			lastContact, failing := s.heartbeats.failingSince(server.ID)
+			member.Healthy = boolPtr(!failing)
+			if failing {
+				member.LastContactMs = durationMsPtr(time.Since(lastContact))
+			}
+		case server.ID == leaderID:
+			lastContact := time.Since(s.raft.LastContact())
+			member.Healthy = boolPtr(lastContact < raft.DefaultConfig().HeartbeatTimeout)
+			member.LastContactMs = durationMsPtr(lastContact)
+		}
+
+		members = append(members, member)
+	}
+
+	return members, nil
+}
+
+// RemoveMember removes a server from the cluster
+func (s *Store) RemoveMember(id string) error {
+	if _, err := s.member(id); err != nil {
+		return err
+	}
+
+	return s.raft.RemoveServer(raft.ServerID(id), 0, 0).Error()
+}
+
+// SetVoter promotes a non-voter to a voter, or demotes a voter to a non-voter
+// that keeps replicating the log but no longer counts toward the quorum
+func (s *Store) SetVoter(id string, voter bool) error {
+	server, err := s.member(id)
+	if err != nil {
+		return err
+	}
+
+	if voter {
+		return s.raft.AddVoter(server.ID, server.Address, 0, 0).Error()
+	}
+	return s.raft.DemoteVoter(server.ID, 0, 0).Error()
+}
+
+func (s *Store) member(id string) (raft.Server, error) {
+	configFuture := s.raft.GetConfiguration()
+	if err := configFuture.Error(); err != nil {
+		return raft.Server{}, err
+	}
+
+	for _, server := range configFuture.Configuration().Servers {
+		if server.ID == raft.ServerID(id) {
+			return server, nil
+		}
+	}
+	return raft.Server{}, fmt.Errorf("%w: %s", ErrUnknownMember, id)
+}
+
+// heartbeatTracker records which followers the leader is failing to reach,
+// from the observations Raft sends, since Raft doesn't expose per-follower
+// contact times
+type heartbeatTracker struct {
+	mu      sync.Mutex
+	failing map[raft.ServerID]time.Time
+}
+
+// observe tracks heartbeats until stop is closed
+func (t *heartbeatTracker) observe(r *raft.Raft, stop <-chan struct{}) {
+	ch := make(chan raft.Observation, 16)
+	observer := raft.NewObserver(ch, false, func(o *raft.Observation) bool {
+		switch o.Data.(type) {
+		case raft.FailedHeartbeatObservation, raft.ResumedHeartbeatObservation, raft.LeaderObservation:
+			return true
+		}
+		return false
+	})
+	r.RegisterObserver(observer)
+	defer r.DeregisterObserver(observer)
+
+	for {
+		var o raft.Observation
+		select {
+		case o = <-ch:
+		case <-stop:
+			return
+		}
+
+		t.mu.Lock()
+		switch data := o.Data.(type) {
+		case raft.FailedHeartbeatObservation:
+			// Keep the time of the first failure's last contact
+			if _, exists := t.failing[data.PeerID]; !exists {
+				t.failing[data.PeerID] = data.LastContact
+			}
+		case raft.ResumedHeartbeatObservation:
+			delete(t.failing, data.PeerID)
+		case raft.LeaderObservation:
+			// A new leader starts tracking from scratch
+			clear(t.failing)
+			if data.LeaderID != "" {
+				metrics.IncrCounter([]string{"raft", "leader", "changes"}, 1)
+			}
+		}
+		t.mu.Unlock()
+	}
+}
+
+func (t *heartbeatTracker) failingSince(id raft.ServerID) (time.Time, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lastContact, failing := t.failing[id]
+	return lastContact, failing
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+func durationMsPtr(d time.Duration) *int64 {
+	ms := d.Milliseconds()
+	return &ms
+}
@@ -0,0 +1,149 @@
+package store
+
+import (
+	"log"
+	"time"
+
+	"raft3d/fsm"
+)
+
+// Strategy chooses a printer for a job among compatible idle printers.
+// Candidates are sorted by printer ID and never empty.
+type Strategy interface {
+	Pick(job fsm.PrintJob, candidates []fsm.Candidate) string
+}
+
+// Strategies maps strategy names, as taken by -schedule-strategy, to their
+// implementations
+var Strategies = map[string]func() Strategy{
+	"fewest-assigned": func() Strategy { return fewestAssigned{} },
+	"most-filament":   func() Strategy { return mostFilament{} },
+	"round-robin":     func() Strategy { return &roundRobin{} },
+}
+
+// fewestAssigned picks the printer that has been given the fewest jobs over
+// its lifetime. Candidates are idle, with nothing printing or queued, so this
+// spreads wear rather than current load.
+type fewestAssigned struct{}
+
+func (fewestAssigned) Pick(job fsm.PrintJob, candidates []fsm.Candidate) string {
+	best := candidates[0]
+	for _, c := range candidates[1:] {
+		if c.Printer.JobsAssigned < best.Printer.JobsAssigned {
+			best = c
+		}
+	}
+	return best.Printer.ID
+}
+
+// mostFilament picks the printer with the most filament available
+type mostFilament struct{}
+
+func (mostFilament) Pick(job fsm.PrintJob, candidates []fsm.Candidate) string {
+	best := candidates[0]
+	for _, c := range candidates[1:] {
+		if c.Available > best.Available {
+			best = c
+		}
+	}
+	return best.Printer.ID
+}
+
+// roundRobin cycles through printers in ID order. The position is local to
+// the leader and restarts after a leader change.
+type roundRobin struct {
+	last string
+}
+
+func (s *roundRobin) Pick(job fsm.PrintJob, candidates []fsm.Candidate) string {
+	for _, c := range candidates {
+		if c.Printer.ID > s.last {
+			s.last = c.Printer.ID
+			return s.last
+		}
+	}
+	s.last = candidates[0].Printer.ID
+	return s.last
+}
+
+// schedulerInterval is how often the leader retries jobs still in the pool,
+// in case no Apply wakes it
+const schedulerInterval = 5 * time.Second
+
+// Scheduler assigns jobs from the unassigned pool to printers. It only acts
+// on the leader, and every assignment goes through the Raft log as an
+// assign_job command that the FSM validates again.
+type Scheduler struct {
+	store    *Store
+	strategy Strategy
+	wake     chan struct{}
+}
+
+func NewScheduler(store *Store, strategy Strategy) *Scheduler {
+	return &Scheduler{store: store, strategy: strategy, wake: make(chan struct{}, 1)}
+}
+
+// Notify asks the scheduler to run a pass soon, without blocking
+func (s *Scheduler) Notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run schedules pending jobs whenever notified or on a timer, until stop is
+// closed
+func (s *Scheduler) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.wake:
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+
+		if s.store.IsLeader() {
+			s.schedule()
+		}
+	}
+}
+
+// schedule makes one pass over the pool and commits the assignments
+func (s *Scheduler) schedule() {
+	for _, cmd := range s.plan(s.store.fsm) {
+		if _, err := s.store.Apply(cmd); err != nil {
+			log.Printf("Failed to assign job %s to printer %s: %v", cmd.JobID, cmd.PrinterID, err)
+			continue
+		}
+		log.Printf("Scheduled job %s on printer %s", cmd.JobID, cmd.PrinterID)
+	}
+}
+
+// plan picks printers for the pool, oldest job first, giving each job at most
+// one printer and each printer at most one job. An assignment only changes
+// its own printer, so planning the whole pass up front sees the same state
+// as applying each assignment in turn.
+func (s *Scheduler) plan(state *fsm.FSM) []fsm.Command {
+	var assignments []fsm.Command
+	taken := make(map[string]bool)
+
+	for _, job := range state.UnassignedJobs() {
+		candidates := state.CompatiblePrinters(job, taken)
+		if len(candidates) == 0 {
+			continue
+		}
+
+		printerID := s.strategy.Pick(job, candidates)
+		taken[printerID] = true
+
+		assignments = append(assignments, fsm.Command{
+			Type:      fsm.CommandAssignJob,
+			JobID:     job.ID,
+			PrinterID: printerID,
+		})
+	}
+	return assignments
+}
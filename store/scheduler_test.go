@@ -0,0 +1,198 @@
+package store
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/raft"
+
+	"raft3d/fsm"
+)
+
+func candidate(id string, jobsAssigned int, available float64) fsm.Candidate {
+	return fsm.Candidate{
+		Printer:   fsm.Printer{ID: id, Status: "idle", JobsAssigned: jobsAssigned},
+		Available: available,
+	}
+}
+
+func TestStrategies(t *testing.T) {
+	tests := []struct {
+		name       string
+		strategy   string
+		candidates [][]fsm.Candidate // one Pick per entry, on the same strategy
+		want       []string
+	}{
+		{
+			name:     "fewest assigned",
+			strategy: "fewest-assigned",
+			candidates: [][]fsm.Candidate{
+				{candidate("printer-1", 3, 100), candidate("printer-2", 1, 10), candidate("printer-3", 2, 500)},
+			},
+			want: []string{"printer-2"},
+		},
+		{
+			name:     "fewest assigned tie goes to the lowest ID",
+			strategy: "fewest-assigned",
+			candidates: [][]fsm.Candidate{
+				{candidate("printer-1", 1, 100), candidate("printer-2", 1, 500)},
+			},
+			want: []string{"printer-1"},
+		},
+		{
+			name:     "most filament",
+			strategy: "most-filament",
+			candidates: [][]fsm.Candidate{
+				{candidate("printer-1", 0, 100), candidate("printer-2", 5, 750), candidate("printer-3", 0, 200)},
+			},
+			want: []string{"printer-2"},
+		},
+		{
+			name:     "most filament tie goes to the lowest ID",
+			strategy: "most-filament",
+			candidates: [][]fsm.Candidate{
+				{candidate("printer-1", 4, 300), candidate("printer-2", 0, 300)},
+			},
+			want: []string{"printer-1"},
+		},
+		{
+			name:     "round robin cycles in ID order",
+			strategy: "round-robin",
+			candidates: [][]fsm.Candidate{
+				{candidate("printer-1", 0, 0), candidate("printer-2", 0, 0), candidate("printer-3", 0, 0)},
+				{candidate("printer-1", 0, 0), candidate("printer-2", 0, 0), candidate("printer-3", 0, 0)},
+				{candidate("printer-1", 0, 0), candidate("printer-2", 0, 0), candidate("printer-3", 0, 0)},
+				{candidate("printer-1", 0, 0), candidate("printer-2", 0, 0), candidate("printer-3", 0, 0)},
+			},
+			want: []string{"printer-1", "printer-2", "printer-3", "printer-1"},
+		},
+		{
+			name:     "round robin skips printers that aren't candidates",
+			strategy: "round-robin",
+			candidates: [][]fsm.Candidate{
+				{candidate("printer-1", 0, 0), candidate("printer-2", 0, 0)},
+				{candidate("printer-1", 0, 0), candidate("printer-3", 0, 0)},
+				{candidate("printer-2", 0, 0)},
+			},
+			want: []string{"printer-1", "printer-3", "printer-2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategy := Strategies[tt.strategy]()
+
+			var got []string
+			for _, candidates := range tt.candidates {
+				got = append(got, strategy.Pick(fsm.PrintJob{}, candidates))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("picked %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// testFSM applies commands to an FSM at consecutive log indexes
+type testFSM struct {
+	*fsm.FSM
+	index uint64
+}
+
+// apply applies cmds in order, failing the test on the first error
+func (f *testFSM) apply(t *testing.T, cmds ...fsm.Command) {
+	t.Helper()
+
+	for _, cmd := range cmds {
+		data, err := fsm.EncodeCommand(cmd)
+		if err != nil {
+			t.Fatalf("EncodeCommand: %v", err)
+		}
+		f.index++
+		result := f.Apply(&raft.Log{Index: f.index, Data: data}).(fsm.ApplyResult)
+		if result.Err != nil {
+			t.Fatalf("%s: %v", cmd.Type, result.Err)
+		}
+	}
+}
+
+// loadedPrinter creates a printer with a fresh spool of material loaded
+func loadedPrinter(n int, material string, weight float64) []fsm.Command {
+	return []fsm.Command{
+		{Type: fsm.CommandCreatePrinter, Printer: &fsm.Printer{Name: fmt.Sprintf("P%d", n), Status: "idle"}},
+		{Type: fsm.CommandCreateFilament, Filament: &fsm.Filament{Type: material, Color: "black", TotalWeight: weight, RemainingWeight: weight}},
+		{Type: fsm.CommandLoadFilament, PrinterID: fmt.Sprintf("printer-%d", n), FilamentID: fmt.Sprintf("filament-%d", n)},
+	}
+}
+
+func poolJob(weight float64, constraints *fsm.JobConstraints) fsm.Command {
+	return fsm.Command{Type: fsm.CommandSubmitJob, Job: &fsm.PrintJob{FilamentWeight: weight, Constraints: constraints}}
+}
+
+func TestPlan(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup [][]fsm.Command
+		want  map[string]string // job ID to printer ID
+	}{
+		{
+			name: "each printer takes one job per pass",
+			setup: [][]fsm.Command{
+				loadedPrinter(1, "PLA", 1000),
+				loadedPrinter(2, "PLA", 1000),
+				{poolJob(10, nil), poolJob(10, nil), poolJob(10, nil)},
+			},
+			want: map[string]string{"job-1": "printer-1", "job-2": "printer-2"},
+		},
+		{
+			name: "a job that fits nowhere doesn't hold up later ones",
+			setup: [][]fsm.Command{
+				loadedPrinter(1, "PLA", 100),
+				{poolJob(500, nil), poolJob(50, nil)},
+			},
+			want: map[string]string{"job-2": "printer-1"},
+		},
+		{
+			name: "constraints narrow the candidates",
+			setup: [][]fsm.Command{
+				loadedPrinter(1, "PLA", 1000),
+				loadedPrinter(2, "PETG", 1000),
+				{poolJob(10, &fsm.JobConstraints{Material: "PETG"}), poolJob(10, &fsm.JobConstraints{Material: "PETG"})},
+			},
+			want: map[string]string{"job-1": "printer-2"},
+		},
+		{
+			name: "busy printers are skipped",
+			setup: [][]fsm.Command{
+				loadedPrinter(1, "PLA", 1000),
+				loadedPrinter(2, "PLA", 1000),
+				{{Type: fsm.CommandSubmitJob, Job: &fsm.PrintJob{PrinterID: "printer-1", FilamentWeight: 10}}},
+				{poolJob(10, nil), poolJob(10, nil)},
+			},
+			want: map[string]string{"job-2": "printer-2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &testFSM{FSM: fsm.New()}
+			for _, cmds := range tt.setup {
+				f.apply(t, cmds...)
+			}
+
+			s := NewScheduler(nil, fewestAssigned{})
+			planned := s.plan(f.FSM)
+			got := make(map[string]string)
+			for _, cmd := range planned {
+				got[cmd.JobID] = cmd.PrinterID
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("planned %v, want %v", got, tt.want)
+			}
+
+			// The FSM must accept every assignment when applied in turn
+			f.apply(t, planned...)
+		})
+	}
+}
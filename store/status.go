@@ -0,0 +1,79 @@
+package store
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"raft3d/fsm"
+)
+
+// Status describes this node's view of the cluster
+type Status struct {
+	ID            string `json:"id"`
+	State         string `json:"state"`
+	LeaderID      string `json:"leader_id"`
+	LeaderAddress string `json:"leader_address"`
+	Term          uint64 `json:"term"`
+
+	CommitIndex  uint64 `json:"commit_index"`
+	AppliedIndex uint64 `json:"applied_index"`
+	LastLogIndex uint64 `json:"last_log_index"`
+
+	LastSnapshot *SnapshotStatus `json:"last_snapshot,omitempty"`
+	Entities     fsm.Counts      `json:"entities"`
+
+	// Stats is raft.Stats() as reported by the library
+	Stats map[string]string `json:"raft_stats"`
+}
+
+// SnapshotStatus describes the newest snapshot on disk
+type SnapshotStatus struct {
+	ID    string    `json:"id"`
+	Index uint64    `json:"index"`
+	Term  uint64    `json:"term"`
+	Size  int64     `json:"size"`
+	Time  time.Time `json:"time"`
+}
+
+// Status reports this node's Raft state, indexes and FSM size
+func (s *Store) Status() Status {
+	leaderAddr, leaderID := s.raft.LeaderWithID()
+
+	status := Status{
+		ID:            s.id,
+		State:         s.raft.State().String(),
+		LeaderID:      string(leaderID),
+		LeaderAddress: string(leaderAddr),
+		Term:          s.raft.CurrentTerm(),
+		CommitIndex:   s.raft.CommitIndex(),
+		AppliedIndex:  s.raft.AppliedIndex(),
+		LastLogIndex:  s.raft.LastIndex(),
+		Entities:      s.fsm.Counts(),
+		Stats:         s.raft.Stats(),
+	}
+
+	// List returns the newest snapshot first
+	if snapshots, err := s.snapshots.List(); err == nil && len(snapshots) > 0 {
+		meta := snapshots[0]
+		status.LastSnapshot = &SnapshotStatus{
+			ID:    meta.ID,
+			Index: meta.Index,
+			Term:  meta.Term,
+			Size:  meta.Size,
+			Time:  snapshotTime(meta.ID),
+		}
+	}
+
+	return status
+}
+
+// snapshotTime recovers when a snapshot was taken from its ID, which the file
+// snapshot store formats as term-index-unixmillis
+func snapshotTime(id string) time.Time {
+	millis, err := strconv.ParseInt(id[strings.LastIndexByte(id, '-')+1:], 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(millis)
+}
@@ -0,0 +1,337 @@
+// Package store runs a Raft node around the FSM: it opens the log and
+// snapshot stores, bootstraps or waits to be added to a cluster, and submits
+// commands through the Raft log.
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/raft"
+	raftboltdb "github.com/hashicorp/raft-boltdb"
+
+	"raft3d/fsm"
+)
+
+// Config describes a node
+type Config struct {
+	// ID is the node's Raft server ID
+	ID string
+	// RaftBind is the address the Raft transport listens on
+	RaftBind string
+	// HTTPAddr is the HTTP address other nodes use to reach this node. It is
+	// replicated whenever the node becomes leader so followers can forward
+	// requests to it.
+	HTTPAddr string
+
+	// DataDir holds the BoltDB log store and the snapshots
+	DataDir           string
+	SnapshotRetain    int
+	SnapshotThreshold uint64
+	SnapshotInterval  time.Duration
+
+	// Bootstrap starts a new cluster. Nodes that join an existing cluster
+	// leave it unset.
+	Bootstrap bool
+	// Peers is the initial membership when bootstrapping, including this
+	// node. Every node of a new cluster is started with the same list, so
+	// none of them has to go first. If empty, the cluster starts with only
+	// this node.
+	Peers []raft.Server
+
+	// Strategy picks printers for unassigned jobs; fewest-assigned if nil
+	Strategy Strategy
+}
+
+// ErrShuttingDown is returned by Apply once Shutdown has started
+var ErrShuttingDown = errors.New("node is shutting down")
+
+// Store is a running Raft node and the FSM it replicates
+type Store struct {
+	id        string
+	raft      *raft.Raft
+	fsm       *fsm.FSM
+	boltStore *raftboltdb.BoltStore
+	snapshots raft.SnapshotStore
+	scheduler *Scheduler
+	events    *eventLog
+
+	heartbeats heartbeatTracker
+
+	// stop is closed when Shutdown starts, ending the background goroutines
+	stop chan struct{}
+
+	// mu guards closing; inflight counts applies in progress so Shutdown can
+	// wait for them
+	mu       sync.Mutex
+	closing  bool
+	inflight sync.WaitGroup
+}
+
+// Open starts a Raft node with a fresh FSM
+func Open(cfg Config) (*Store, error) {
+	if cfg.Bootstrap && len(cfg.Peers) > 0 && !hasServer(cfg.Peers, cfg.ID) {
+		return nil, fmt.Errorf("peer list does not include this node (%s)", cfg.ID)
+	}
+
+	strategy := cfg.Strategy
+	if strategy == nil {
+		strategy = fewestAssigned{}
+	}
+
+	s := &Store{
+		id:         cfg.ID,
+		fsm:        fsm.New(),
+		events:     newEventLog(),
+		heartbeats: heartbeatTracker{failing: make(map[raft.ServerID]time.Time)},
+		stop:       make(chan struct{}),
+	}
+
+	// Every applied command wakes the scheduler and is buffered for event streams
+	s.scheduler = NewScheduler(s, strategy)
+	s.fsm.SetNotify(s.publishEvent)
+
+	// Raft config
+	config := raft.DefaultConfig()
+	config.LocalID = raft.ServerID(cfg.ID)
+	config.SnapshotThreshold = cfg.SnapshotThreshold
+	config.SnapshotInterval = cfg.SnapshotInterval
+	config.Logger = hclog.New(&hclog.LoggerOptions{
+		Name:  "raft",
+		Level: hclog.LevelFromString("INFO"),
+	})
+
+	// Raft transport
+	addr, err := net.ResolveTCPAddr("tcp", cfg.RaftBind)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve TCP address: %w", err)
+	}
+
+	transport, err := raft.NewTCPTransport(cfg.RaftBind, addr, 3, 10*time.Second, os.Stdout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transport: %w", err)
+	}
+
+	// Other nodes reach this one at its address in the shared peer list, so
+	// it must be the address Raft actually advertises
+	for _, server := range cfg.Peers {
+		if server.ID == raft.ServerID(cfg.ID) && server.Address != transport.LocalAddr() {
+			transport.Close()
+			return nil, fmt.Errorf("peer list gives this node (%s) address %s, but it listens on %s", cfg.ID, server.Address, transport.LocalAddr())
+		}
+	}
+
+	// Raft stores
+	if err := os.MkdirAll(cfg.DataDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	s.boltStore, err = raftboltdb.NewBoltStore(filepath.Join(cfg.DataDir, fmt.Sprintf("raft-%s.db", cfg.ID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bolt store: %w", err)
+	}
+
+	s.snapshots, err = raft.NewFileSnapshotStore(filepath.Join(cfg.DataDir, fmt.Sprintf("snapshots-%s", cfg.ID)), cfg.SnapshotRetain, os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot store: %w", err)
+	}
+
+	// Create Raft node
+	s.raft, err = raft.NewRaft(config, s.fsm, s.boltStore, s.boltStore, s.snapshots, transport)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create raft node: %w", err)
+	}
+
+	if cfg.Bootstrap {
+		servers := cfg.Peers
+		if len(servers) == 0 {
+			servers = []raft.Server{
+				{
+					ID:      raft.ServerID(cfg.ID),
+					Address: transport.LocalAddr(),
+				},
+			}
+		}
+
+		// Bootstrapping again after a restart fails harmlessly; the existing
+		// state wins
+		err := s.raft.BootstrapCluster(raft.Configuration{Servers: servers}).Error()
+		switch {
+		case err == nil:
+			log.Printf("Bootstrapped cluster with %d server(s)", len(servers))
+		case errors.Is(err, raft.ErrCantBootstrap):
+			log.Println("Existing Raft state found, skipping bootstrap")
+		default:
+			return nil, fmt.Errorf("failed to bootstrap cluster: %w", err)
+		}
+	}
+
+	if cfg.HTTPAddr != "" {
+		go s.registerSelfOnLeadership(cfg.HTTPAddr)
+	}
+	go s.scheduler.Run(s.stop)
+	go s.heartbeats.observe(s.raft, s.stop)
+
+	return s, nil
+}
+
+// ID returns the node's Raft server ID
+func (s *Store) ID() string {
+	return s.id
+}
+
+// Raft returns the underlying Raft node
+func (s *Store) Raft() *raft.Raft {
+	return s.raft
+}
+
+// FSM returns the replicated state machine
+func (s *Store) FSM() *fsm.FSM {
+	return s.fsm
+}
+
+// IsLeader reports whether this node is currently the leader
+func (s *Store) IsLeader() bool {
+	return s.raft.State() == raft.Leader
+}
+
+// Apply serializes a command, applies it to the Raft log and unpacks the
+// FSM's ApplyResult into the resulting entity or the rejection
+func (s *Store) Apply(command fsm.Command) (interface{}, error) {
+	s.mu.Lock()
+	if s.closing {
+		s.mu.Unlock()
+		return nil, ErrShuttingDown
+	}
+	s.inflight.Add(1)
+	s.mu.Unlock()
+	defer s.inflight.Done()
+	defer metrics.MeasureSince([]string{"store", "apply"}, time.Now())
+
+	commandBytes, err := fsm.EncodeCommand(command)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize command: %w", err)
+	}
+
+	// Apply command to Raft log
+	applyFuture := s.raft.Apply(commandBytes, 0)
+	if err := applyFuture.Error(); err != nil {
+		return nil, fmt.Errorf("raft apply failed: %w", err)
+	}
+
+	result, ok := applyFuture.Response().(fsm.ApplyResult)
+	if !ok {
+		return nil, fmt.Errorf("unexpected FSM response %T", applyFuture.Response())
+	}
+
+	return result.Entity, result.Err
+}
+
+// Shutdown drains the node: it refuses new commands, waits for the ones in
+// flight, hands leadership to another voter if this node is the leader, then
+// stops Raft and closes the log store. In-flight commands are waited for
+// before the transfer, which would otherwise fail them. ctx bounds the drain
+// and the transfer only: Raft is always stopped and the log store always
+// closed, however long that takes, so the node never exits uncleanly.
+func (s *Store) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closing {
+		s.closing = true
+		close(s.stop)
+	}
+	s.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		s.inflight.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		log.Printf("Shutting down with commands still in flight: %v", ctx.Err())
+	}
+
+	if s.IsLeader() && s.hasOtherVoters() {
+		if err := waitFuture(ctx, s.raft.LeadershipTransfer()); err != nil {
+			log.Printf("Leadership transfer failed: %v", err)
+		} else {
+			log.Println("Transferred leadership")
+		}
+	}
+
+	shutdownErr := s.raft.Shutdown().Error()
+	if err := s.boltStore.Close(); err != nil {
+		return fmt.Errorf("failed to close log store: %w", err)
+	}
+	if shutdownErr != nil {
+		return fmt.Errorf("failed to shut down raft: %w", shutdownErr)
+	}
+
+	return nil
+}
+
+// TransferLeadership hands leadership to the member with the given ID, or to
+// the most up-to-date voter if id is empty. It returns the new leader once
+// this node has heard from it, or an empty ID if that takes longer than an
+// election.
+func (s *Store) TransferLeadership(id string) (raft.ServerID, error) {
+	var future raft.Future
+	if id == "" {
+		future = s.raft.LeadershipTransfer()
+	} else {
+		server, err := s.member(id)
+		if err != nil {
+			return "", err
+		}
+		future = s.raft.LeadershipTransferToServer(server.ID, server.Address)
+	}
+	if err := future.Error(); err != nil {
+		return "", err
+	}
+
+	deadline := time.Now().Add(raft.DefaultConfig().ElectionTimeout)
+	for time.Now().Before(deadline) {
+		if _, leaderID := s.raft.LeaderWithID(); leaderID != "" && leaderID != raft.ServerID(s.id) {
+			return leaderID, nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return "", nil
+}
+
+func (s *Store) hasOtherVoters() bool {
+	configFuture := s.raft.GetConfiguration()
+	if configFuture.Error() != nil {
+		return false
+	}
+
+	for _, server := range configFuture.Configuration().Servers {
+		if server.ID != raft.ServerID(s.id) && server.Suffrage == raft.Voter {
+			return true
+		}
+	}
+	return false
+}
+
+// waitFuture waits for a Raft future, giving up when ctx is done
+func waitFuture(ctx context.Context, future raft.Future) error {
+	done := make(chan error, 1)
+	go func() { done <- future.Error() }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
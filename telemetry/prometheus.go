@@ -0,0 +1,99 @@
+package telemetry
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	metrics "github.com/armon/go-metrics"
+)
+
+// ContentType is the Prometheus text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Prometheus metric types
+const (
+	TypeGauge   = "gauge"
+	TypeCounter = "counter"
+	TypeSummary = "summary"
+)
+
+// Family is a metric name with its type and every labelled sample of it
+type Family struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []Sample
+}
+
+// Sample is one value of a family. Suffix is appended to the family name, for
+// the _sum and _count of a summary.
+type Sample struct {
+	Suffix string
+	Labels []metrics.Label
+	Value  float64
+}
+
+// Write renders families in the Prometheus text format
+func Write(w io.Writer, families []Family) error {
+	bw := bufio.NewWriter(w)
+
+	for _, family := range families {
+		name := metricName(family.Name)
+		if family.Help != "" {
+			fmt.Fprintf(bw, "# HELP %s %s\n", name, strings.ReplaceAll(family.Help, "\n", " "))
+		}
+		fmt.Fprintf(bw, "# TYPE %s %s\n", name, family.Type)
+
+		for _, sample := range family.Samples {
+			bw.WriteString(name + sample.Suffix)
+			writeLabels(bw, sample.Labels)
+			bw.WriteString(" " + formatFloat(sample.Value) + "\n")
+		}
+	}
+
+	return bw.Flush()
+}
+
+func writeLabels(bw *bufio.Writer, labels []metrics.Label) {
+	if len(labels) == 0 {
+		return
+	}
+
+	bw.WriteByte('{')
+	for i, label := range labels {
+		if i > 0 {
+			bw.WriteByte(',')
+		}
+		bw.WriteString(metricName(label.Name) + `="` + labelEscaper.Replace(label.Value) + `"`)
+	}
+	bw.WriteByte('}')
+}
+
+// labelEscaper escapes label values as the text format requires
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// metricName replaces the characters Prometheus doesn't allow in names
+func metricName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == ':' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,101 @@
+package telemetry
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	metrics "github.com/armon/go-metrics"
+)
+
+func TestWriteSink(t *testing.T) {
+	sink := NewSink()
+
+	sink.SetGauge([]string{"raft3d", "raft", "state", "leader"}, 1)
+	sink.SetGaugeWithLabels([]string{"raft3d", "node"}, 2, []metrics.Label{
+		{Name: "peer-id", Value: "n2"},
+		{Name: "note", Value: "say \"hi\"\\now\nthen"},
+	})
+	sink.IncrCounter([]string{"raft3d", "http", "requests"}, 3)
+	sink.IncrCounter([]string{"raft3d", "http", "requests"}, 4)
+
+	for i := 1; i <= 10; i++ {
+		sink.AddSampleWithLabels([]string{"raft3d", "raft", "apply"}, float32(i), []metrics.Label{{Name: "op", Value: "put"}})
+	}
+
+	// Only the last sampleWindow values feed the quantiles, while the sum and
+	// count cover every sample
+	for i := 0; i < sampleWindow; i++ {
+		sink.AddSample([]string{"raft3d", "raft", "commit"}, 1000)
+	}
+	for i := 0; i < 976; i++ {
+		sink.AddSample([]string{"raft3d", "raft", "commit"}, 1)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, sink.Families()); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	want := `# TYPE raft3d_node gauge
+raft3d_node{peer_id="n2",note="say \"hi\"\\now\nthen"} 2
+# TYPE raft3d_raft_state_leader gauge
+raft3d_raft_state_leader 1
+# TYPE raft3d_http_requests_total counter
+raft3d_http_requests_total 7
+# TYPE raft3d_raft_apply summary
+raft3d_raft_apply{op="put",quantile="0.5"} 6
+raft3d_raft_apply{op="put",quantile="0.9"} 9
+raft3d_raft_apply{op="put",quantile="0.99"} 10
+raft3d_raft_apply_sum{op="put"} 55
+raft3d_raft_apply_count{op="put"} 10
+# TYPE raft3d_raft_commit summary
+raft3d_raft_commit{quantile="0.5"} 1
+raft3d_raft_commit{quantile="0.9"} 1
+raft3d_raft_commit{quantile="0.99"} 1000
+raft3d_raft_commit_sum 1.024976e+06
+raft3d_raft_commit_count 2000
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteFamilies(t *testing.T) {
+	families := []Family{
+		{
+			Name: "raft3d.up",
+			Help: "Whether the node\nis up",
+			Type: TypeGauge,
+			Samples: []Sample{
+				{Value: 1},
+			},
+		},
+		{
+			Name: "raft3d_peer_lag",
+			Type: TypeGauge,
+			Samples: []Sample{
+				{Labels: []metrics.Label{{Name: "peer", Value: "n1"}}, Value: 0.25},
+				{Labels: []metrics.Label{{Name: "peer", Value: "n2"}}, Value: math.Inf(1)},
+				{Labels: []metrics.Label{{Name: "peer", Value: "n3"}}, Value: math.NaN()},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, families); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	want := `# HELP raft3d_up Whether the node is up
+# TYPE raft3d_up gauge
+raft3d_up 1
+# TYPE raft3d_peer_lag gauge
+raft3d_peer_lag{peer="n1"} 0.25
+raft3d_peer_lag{peer="n2"} +Inf
+raft3d_peer_lag{peer="n3"} NaN
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
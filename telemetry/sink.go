@@ -0,0 +1,199 @@
+// Package telemetry collects the metrics Raft and the node emit through
+// go-metrics and renders them in the Prometheus text format.
+package telemetry
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	metrics "github.com/armon/go-metrics"
+)
+
+// ServiceName prefixes every metric emitted through go-metrics
+const ServiceName = "raft3d"
+
+// sampleWindow is how many recent samples of each series are kept to compute
+// quantiles
+const sampleWindow = 1024
+
+// quantiles reported for every sampled series
+var quantiles = []float64{0.5, 0.9, 0.99}
+
+// Setup installs a Sink as the global go-metrics sink, which is where
+// hashicorp/raft reports its metrics, and returns it
+func Setup() (*Sink, error) {
+	cfg := metrics.DefaultConfig(ServiceName)
+	cfg.EnableHostname = false
+	// Raft also reports these per peer under the peer's ID in the metric
+	// name; the labelled versions are kept instead
+	cfg.BlockedPrefixes = []string{
+		ServiceName + ".raft.replication.appendEntries.rpc.",
+		ServiceName + ".raft.replication.appendEntries.logs.",
+		ServiceName + ".raft.replication.heartbeat.",
+		ServiceName + ".raft.replication.installSnapshot.",
+	}
+
+	sink := NewSink()
+	if _, err := metrics.NewGlobal(cfg, sink); err != nil {
+		return nil, err
+	}
+	return sink, nil
+}
+
+// Sink is a go-metrics sink that keeps the latest gauges, cumulative counters
+// and a window of recent samples for every series, for scraping
+type Sink struct {
+	mu       sync.Mutex
+	gauges   map[string]*series
+	counters map[string]*series
+	samples  map[string]*series
+}
+
+// series is one metric name with one set of label values
+type series struct {
+	name   string
+	labels []metrics.Label
+
+	value float64 // gauge value or counter total
+
+	// Samples only
+	count  uint64
+	sum    float64
+	window []float64
+	next   int
+}
+
+// NewSink returns an empty Sink
+func NewSink() *Sink {
+	return &Sink{
+		gauges:   make(map[string]*series),
+		counters: make(map[string]*series),
+		samples:  make(map[string]*series),
+	}
+}
+
+func (s *Sink) SetGauge(key []string, val float32) {
+	s.SetGaugeWithLabels(key, val, nil)
+}
+
+func (s *Sink) SetGaugeWithLabels(key []string, val float32, labels []metrics.Label) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lookup(s.gauges, key, labels).value = float64(val)
+}
+
+// EmitKey is not supported by the Prometheus format and is ignored
+func (s *Sink) EmitKey(key []string, val float32) {}
+
+func (s *Sink) IncrCounter(key []string, val float32) {
+	s.IncrCounterWithLabels(key, val, nil)
+}
+
+func (s *Sink) IncrCounterWithLabels(key []string, val float32, labels []metrics.Label) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lookup(s.counters, key, labels).value += float64(val)
+}
+
+func (s *Sink) AddSample(key []string, val float32) {
+	s.AddSampleWithLabels(key, val, nil)
+}
+
+func (s *Sink) AddSampleWithLabels(key []string, val float32, labels []metrics.Label) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sample := lookup(s.samples, key, labels)
+	sample.count++
+	sample.sum += float64(val)
+	if len(sample.window) < sampleWindow {
+		sample.window = append(sample.window, float64(val))
+	} else {
+		sample.window[sample.next] = float64(val)
+		sample.next = (sample.next + 1) % sampleWindow
+	}
+}
+
+// Families returns everything collected so far. Samples, which go-metrics
+// uses for timers in milliseconds, become summaries.
+func (s *Sink) Families() []Family {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var families []Family
+	families = appendFamilies(families, s.gauges, TypeGauge, "", func(ser *series) []Sample {
+		return []Sample{{Labels: ser.labels, Value: ser.value}}
+	})
+	families = appendFamilies(families, s.counters, TypeCounter, "_total", func(ser *series) []Sample {
+		return []Sample{{Labels: ser.labels, Value: ser.value}}
+	})
+	families = appendFamilies(families, s.samples, TypeSummary, "", summarize)
+	return families
+}
+
+// appendFamilies groups every series of one kind into families by name
+func appendFamilies(families []Family, all map[string]*series, typ, suffix string, samples func(*series) []Sample) []Family {
+	keys := make([]string, 0, len(all))
+	for key := range all {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	byName := make(map[string]int)
+	for _, key := range keys {
+		ser := all[key]
+		i, exists := byName[ser.name]
+		if !exists {
+			i = len(families)
+			byName[ser.name] = i
+			families = append(families, Family{Name: ser.name + suffix, Type: typ})
+		}
+		families[i].Samples = append(families[i].Samples, samples(ser)...)
+	}
+	return families
+}
+
+// summarize reports quantiles over the recent window, and the count and sum
+// of every sample seen
+func summarize(ser *series) []Sample {
+	window := append([]float64(nil), ser.window...)
+	sort.Float64s(window)
+
+	var samples []Sample
+	for _, q := range quantiles {
+		labels := append(append([]metrics.Label(nil), ser.labels...), metrics.Label{Name: "quantile", Value: formatFloat(q)})
+		samples = append(samples, Sample{Labels: labels, Value: quantile(window, q)})
+	}
+	return append(samples,
+		Sample{Suffix: "_sum", Labels: ser.labels, Value: ser.sum},
+		Sample{Suffix: "_count", Labels: ser.labels, Value: float64(ser.count)},
+	)
+}
+
+// quantile picks the q-quantile of sorted values by nearest rank
+func quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[int(q*float64(len(sorted)-1)+0.5)]
+}
+
+// lookup finds or creates the series for key and labels in all
+func lookup(all map[string]*series, key []string, labels []metrics.Label) *series {
+	name := metricName(strings.Join(key, "_"))
+
+	id := name
+	for _, label := range labels {
+		id += "\xff" + label.Name + "=" + label.Value
+	}
+
+	ser, exists := all[id]
+	if !exists {
+		ser = &series{name: name, labels: append([]metrics.Label(nil), labels...)}
+		all[id] = ser
+	}
+	return ser
+}